@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const commons = "commons.wikimedia.org"
+
+// Alt text sources.
+const (
+	altTemplate = "template" // derived from the file name
+	altCommons  = "commons"  // commons file description
+)
+
+// altMaxLen caps commons descriptions, which can be paragraphs.
+const altMaxLen = 160
+
+// describeMap templates the alt text of a map from its file name patterns,
+// subject is the country or a placeholder for question cards.
+func describeMap(fileName, subject string) string {
+	s := strings.ToLower(fileName)
+	switch {
+	case strings.Contains(s, "orthographic"):
+		return "Orthographic projection map highlighting " + subject
+	case strings.Contains(s, "on_the_globe"):
+		return "Globe map highlighting " + subject
+	case strings.Contains(s, "in_its_region"):
+		return "Regional map highlighting " + subject
+	case strings.Contains(s, "location"):
+		return "Location map of " + subject
+	}
+	return "Map of " + subject
+}
+
+// getFileDescription returns the plain text commons description of the file.
+func getFileDescription(name string, refresh bool) (string, error) {
+	fname := filepath.Join("pages", commons, name+".json")
+	params := url.Values{
+		"action": {"query"},
+		"titles": {"File:" + name},
+		"prop":   {"imageinfo"},
+		"iiprop": {"extmetadata"},
+		"format": {"json"},
+		"maxlag": {maxLag},
+	}
+	body, err := queryLocked(fname, "https://"+commons+"/w/api.php?"+params.Encode(), refresh)
+	if err != nil {
+		return "", err
+	}
+
+	var rsp struct {
+		Query struct {
+			Pages map[string]struct {
+				ImageInfo []struct {
+					ExtMetadata struct {
+						ImageDescription struct {
+							Value string `json:"value"`
+						} `json:"ImageDescription"`
+					} `json:"extmetadata"`
+				} `json:"imageinfo"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	if err := json.Unmarshal(body, &rsp); err != nil {
+		return "", fmt.Errorf("%s: %w", fname, err)
+	}
+	for _, p := range rsp.Query.Pages {
+		for _, info := range p.ImageInfo {
+			s := reHTMLTag.ReplaceAllString(info.ExtMetadata.ImageDescription.Value, "")
+			s = strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+			if i := strings.Index(s, ". "); i > -1 {
+				s = s[:i]
+			}
+			if r := []rune(s); len(r) > altMaxLen {
+				s = strings.TrimSpace(string(r[:altMaxLen])) + "…"
+			}
+			return s, nil
+		}
+	}
+	return "", nil
+}
+
+// redact hides the name in question alt text.
+func redact(s, name string) string {
+	re := regexp.MustCompile(`(?i)(the )?` + regexp.QuoteMeta(name))
+	return re.ReplaceAllString(s, "a country")
+}
+
+// describeImages sets the alt text of the country map and flag images.
+func describeImages(c *Country, mapName, flagName string, refresh bool) error {
+	c.MapAlt = describeMap(mapName, c.Name)
+	c.MapAltHidden = describeMap(mapName, "a country")
+	c.FlagAlt = "Flag of " + c.Name
+	c.FlagAltHidden = "Flag of a country"
+	if *flagAltText != altCommons {
+		return nil
+	}
+
+	mapDesc, err := getFileDescription(mapName, refresh)
+	if err != nil {
+		return err
+	}
+	if mapDesc != "" {
+		c.MapAlt, c.MapAltHidden = mapDesc, redact(mapDesc, c.Name)
+	} else {
+		report.fallback(c.UName, "map alt text")
+	}
+	flagDesc, err := getFileDescription(flagName, refresh)
+	if err != nil {
+		return err
+	}
+	if flagDesc != "" {
+		c.FlagAlt, c.FlagAltHidden = flagDesc, redact(flagDesc, c.Name)
+	} else {
+		report.fallback(c.UName, "flag alt text")
+	}
+	return nil
+}
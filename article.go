@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Article policies, how a leading "The" of a country name is handled.
+const (
+	articleKeep  = "keep"  // The Gambia, as listed
+	articleStrip = "strip" // Gambia
+)
+
+func validArticle(policy string) error {
+	switch policy {
+	case articleKeep, articleStrip:
+		return nil
+	}
+	return fmt.Errorf("invalid article policy %q, want %s or %s", policy, articleKeep, articleStrip)
+}
+
+// stripArticle returns the name without a leading "The", in either the
+// display or url form.
+func stripArticle(name string) string {
+	for _, the := range []string{"The ", "the ", "The_", "the_"} {
+		if strings.HasPrefix(name, the) && len(name) > len(the) {
+			return name[len(the):]
+		}
+	}
+	return name
+}
+
+// applyArticle names the country by the --article policy, accepting the
+// other form as an alternative answer. An article of the listed name or of
+// the page it redirects to is capitalized, e.g. the Netherlands.
+func applyArticle(c *Country) {
+	bare := stripArticle(c.Name)
+	if bare == c.Name {
+		// Bahamas redirects to The Bahamas.
+		title := stripArticle(c.Title)
+		if title == c.Title || title != bare {
+			return
+		}
+	}
+	full := "The " + bare
+	c.Name = full
+	other := bare
+	if *flagArticle == articleStrip {
+		c.Name, other = bare, full
+	}
+	addAltName(c, other)
+}
+
+// addAltName accepts the name as the first alternative answer, dropping
+// alternatives equal to it or to the country name.
+func addAltName(c *Country, name string) {
+	alts := []string{name}
+	for _, alt := range c.AltNames {
+		if !strings.EqualFold(alt, c.Name) && !strings.EqualFold(alt, name) {
+			alts = append(alts, alt)
+		}
+	}
+	c.AltNames = alts
+}
+
+// countryFileName returns the name of the country's card files, the url
+// name by the --article policy. It doesn't follow --name-style so styled
+// decks keep their paths.
+func countryFileName(c *Country) string {
+	if *flagArticle == articleStrip {
+		return stripArticle(c.UName)
+	}
+	return c.UName
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStripArticle(t *testing.T) {
+	tests := map[string]string{
+		"The Gambia":       "Gambia",
+		"the Netherlands":  "Netherlands",
+		"The_Bahamas":      "Bahamas",
+		"The":              "The",
+		"Theodoria":        "Theodoria",
+		"Gambia":           "Gambia",
+		"Isle of the Dead": "Isle of the Dead",
+	}
+	for in, want := range tests {
+		if got := stripArticle(in); got != want {
+			t.Errorf("stripArticle(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestApplyArticle(t *testing.T) {
+	tests := []struct {
+		policy      string
+		name, title string
+		wantName    string
+		wantAlts    []string
+	}{
+		{articleKeep, "The Gambia", "The Gambia", "The Gambia", []string{"Gambia"}},
+		{articleStrip, "The Gambia", "The Gambia", "Gambia", []string{"The Gambia"}},
+		{articleKeep, "the Netherlands", "Netherlands", "The Netherlands", []string{"Netherlands"}},
+		{articleStrip, "the Netherlands", "Netherlands", "Netherlands", []string{"The Netherlands"}},
+		// Redirected to the page with the article.
+		{articleKeep, "Bahamas", "The Bahamas", "The Bahamas", []string{"Bahamas"}},
+		{articleStrip, "Bahamas", "The Bahamas", "Bahamas", []string{"The Bahamas"}},
+		{articleKeep, "Ghana", "Ghana", "Ghana", nil},
+		{articleStrip, "Ghana", "", "Ghana", nil},
+		// Styled names aren't the page name.
+		{articleKeep, "Republic of The Gambia", "The Gambia", "Republic of The Gambia", nil},
+	}
+	old := *flagArticle
+	defer func() { *flagArticle = old }()
+	for _, tt := range tests {
+		*flagArticle = tt.policy
+		c := &Country{Name: tt.name, Title: tt.title}
+		applyArticle(c)
+		if c.Name != tt.wantName || !reflect.DeepEqual(c.AltNames, tt.wantAlts) {
+			t.Errorf("%s %q: got %q %q, want %q %q", tt.policy, tt.name, c.Name, c.AltNames, tt.wantName, tt.wantAlts)
+		}
+	}
+}
+
+func TestArticleFileNames(t *testing.T) {
+	oldArticle, oldLayout := *flagArticle, *flagLayout
+	defer func() { *flagArticle, *flagLayout = oldArticle, oldLayout }()
+	*flagArticle = articleStrip
+
+	c := &Country{Name: "The Gambia", UName: "The_Gambia"}
+	if got := countryFileName(c); got != "Gambia" {
+		t.Errorf("countryFileName = %q, want Gambia", got)
+	}
+	*flagLayout = layoutCountry
+	if got, want := deckDir(c, "flags"), filepath.Join("countries", "Gambia"); got != want {
+		t.Errorf("deckDir = %q, want %q", got, want)
+	}
+	// Other cards keep their names.
+	if got := cardName("The_Americas"); got != "The_Americas" {
+		t.Errorf("cardName(The_Americas) = %q", got)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// overrideFields are the fields with overrides, in extraction order.
+var overrideFields = []string{"map", "flag", "capital"}
+
+// extractField extracts the field from the page ignoring overrides.
+func extractField(field string, src *Source) (string, error) {
+	text, uname := src.Text(), toURLName(src.Page.Title)
+	switch field {
+	case "map":
+		name, _, err := extractMapName(text, uname, src.Refresh)
+		return name, err
+	case "flag":
+		name, _, err := extractFlagName(text, uname, src.Refresh)
+		return name, err
+	case "capital":
+		return extractCapital(text)
+	}
+	return "", fmt.Errorf("unknown field %q", field)
+}
+
+// auditOverrides re-extracts every overridden field ignoring the overrides
+// and reports which overrides are unnecessary, as the extraction now gives
+// the same value, and which are still required.
+func auditOverrides(w io.Writer) error {
+	if err := overrides.read(overridesFile); err != nil {
+		return err
+	}
+	if err := lock.read(lockFile); err != nil {
+		return err
+	}
+	names := []string{*flagCountry}
+	if *flagCountry == "" {
+		var err error
+		if names, err = readCountryList("countries.txt"); err != nil {
+			return err
+		}
+	}
+
+	// Overrides of countries no longer listed are unnecessary.
+	unknown := make(map[string]bool)
+	for _, field := range overrideFields {
+		for uname := range builtinOverrides[field] {
+			unknown[uname] = true
+		}
+	}
+	overrides.mu.Lock()
+	for uname := range overrides.fields {
+		unknown[uname] = true
+	}
+	overrides.mu.Unlock()
+
+	var required, unnecessary int
+	for _, name := range names {
+		src, err := fetchCountry(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		uname := toURLName(src.Page.Title)
+		delete(unknown, uname)
+		for _, field := range overrideFields {
+			source := overridesFile
+			x, ok := overrides.get(uname, field)
+			if !ok {
+				source = "builtin"
+				if x, ok = builtinOverrides[field][uname]; !ok {
+					continue
+				}
+			}
+			extracted, err := extractField(field, src)
+			switch {
+			case err != nil:
+				required++
+				fmt.Fprintf(w, "required\t%s %s (%s): %v\n", uname, field, source, err)
+			case extracted != x:
+				required++
+				fmt.Fprintf(w, "required\t%s %s (%s): extracted %q, override %q\n", uname, field, source, extracted, x)
+			default:
+				unnecessary++
+				fmt.Fprintf(w, "unnecessary\t%s %s (%s): extracted %q\n", uname, field, source, extracted)
+			}
+		}
+	}
+	if *flagCountry == "" {
+		var stale []string
+		for uname := range unknown {
+			stale = append(stale, uname)
+		}
+		sort.Strings(stale)
+		for _, uname := range stale {
+			unnecessary++
+			fmt.Fprintf(w, "unnecessary\t%s: not in countries.txt\n", uname)
+		}
+	}
+	fmt.Fprintf(w, "%d required, %d unnecessary overrides\n", required, unnecessary)
+	return nil
+}
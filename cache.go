@@ -0,0 +1,183 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Cached pages are gzipped, wikitext compresses around 5x.
+const gzExt = ".gz"
+
+// gzReader closes both the gzip stream and the underlying file.
+type gzReader struct {
+	*gzip.Reader
+	f *os.File
+}
+
+func (r *gzReader) Close() error {
+	r.Reader.Close()
+	return r.f.Close()
+}
+
+// openCache opens the cache file at path, transparently decompressing
+// gzipped files. Uncompressed files from older caches are still read.
+func openCache(path string) (io.ReadCloser, error) {
+	if f, err := os.Open(path + gzExt); err == nil {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path+gzExt, err)
+		}
+		return &gzReader{Reader: zr, f: f}, nil
+	}
+	return os.Open(path)
+}
+
+// writeCache streams r to the cache file at path, gzipped if compress is set.
+// The file is written to a temporary file first so an interrupted download
+// isn't cached.
+func writeCache(path string, r io.Reader, compress bool) error {
+	if compress {
+		path += gzExt
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".fetch-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	var w io.WriteCloser = tmp
+	if compress {
+		w = gzip.NewWriter(tmp)
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if compress {
+		if err := w.Close(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0666); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	// Remove any stale uncompressed copy.
+	if compress {
+		os.Remove(strings.TrimSuffix(path, gzExt))
+	}
+	return nil
+}
+
+// gzSize returns the uncompressed size recorded in the gzip trailer.
+func gzSize(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(-4, io.SeekEnd); err != nil {
+		return 0, err
+	}
+	var n uint32
+	if err := binary.Read(f, binary.LittleEndian, &n); err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+// cacheStat is the usage of one type of cached data.
+type cacheStat struct {
+	Files int
+	Size  int64 // on disk
+	Raw   int64 // uncompressed
+}
+
+// cacheStats prints the size of the page and file caches per type. Pages are
+// grouped by host.
+func cacheStats(w io.Writer) error {
+	stats := make(map[string]*cacheStat)
+	for _, root := range []string{"pages", "files"} {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			kind := filepath.Dir(path)
+			if root == "pages" && kind == root {
+				kind = filepath.Join(root, wikipedia)
+			}
+			s, ok := stats[kind]
+			if !ok {
+				s = &cacheStat{}
+				stats[kind] = s
+			}
+			s.Files++
+			s.Size += info.Size()
+			raw := info.Size()
+			if strings.HasSuffix(path, gzExt) {
+				if raw, err = gzSize(path); err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+			}
+			s.Raw += raw
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	kinds := make([]string, 0, len(stats))
+	for k := range stats {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	var total cacheStat
+	fmt.Fprintf(w, "%-32s %8s %12s %12s\n", "CACHE", "FILES", "SIZE", "RAW")
+	for _, k := range kinds {
+		s := stats[k]
+		fmt.Fprintf(w, "%-32s %8d %12s %12s\n", k, s.Files, formatBytes(s.Size), formatBytes(s.Raw))
+		total.Files += s.Files
+		total.Size += s.Size
+		total.Raw += s.Raw
+	}
+	fmt.Fprintf(w, "%-32s %8d %12s %12s\n", "total", total.Files, formatBytes(total.Size), formatBytes(total.Raw))
+	return nil
+}
+
+// formatBytes returns n in human readable units, e.g. 4.2 MB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
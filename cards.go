@@ -0,0 +1,518 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dustin/go-wikiparse"
+)
+
+// Source is the fetched data a country is extracted from.
+type Source struct {
+	Page    *wikiparse.Page
+	Refresh bool
+
+	done map[string]bool // extractions shared by card types
+}
+
+// Text returns the wikitext of the country page.
+func (s *Source) Text() string {
+	return s.Page.Revisions[0].Text
+}
+
+// once reports whether the shared extraction is yet to run for the country,
+// so card types selected alone by --decks extract what they need.
+func (s *Source) once(key string) bool {
+	if s.done[key] {
+		return false
+	}
+	if s.done == nil {
+		s.done = make(map[string]bool)
+	}
+	s.done[key] = true
+	return true
+}
+
+// CardType generates a deck of cards per country. New decks are added by
+// registering a CardType.
+type CardType interface {
+	// Name of the deck, e.g. "flags".
+	Name() string
+	// Enabled reports whether the deck is generated this run.
+	Enabled() bool
+	// Extract sets the fields the deck needs on the country.
+	Extract(c *Country, src *Source) error
+	// Render writes the country's cards and media.
+	Render(c *Country, src *Source) error
+}
+
+// Aggregator is implemented by card types that also render cards computed
+// across all countries.
+type Aggregator interface {
+	Aggregate(countries []Country) error
+}
+
+var cardTypes []CardType
+
+func registerCardType(t CardType) {
+	cardTypes = append(cardTypes, t)
+}
+
+// selectedDecks are the card types named by --decks, nil to generate the
+// types enabled by their flags.
+var selectedDecks map[string]bool
+
+// parseDecks parses the comma separated card type names.
+func parseDecks(s string) (map[string]bool, error) {
+	known := make(map[string]bool, len(cardTypes))
+	var names []string
+	for _, t := range cardTypes {
+		known[t.Name()] = true
+		names = append(names, t.Name())
+	}
+	decks := make(map[string]bool)
+	for _, name := range splitList(s) {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown deck %q, want one of %s", name, strings.Join(names, ", "))
+		}
+		decks[name] = true
+	}
+	return decks, nil
+}
+
+// enabledCardTypes returns the card types generated this run, only those
+// selected with --decks if set.
+func enabledCardTypes() []CardType {
+	var ts []CardType
+	for _, t := range cardTypes {
+		if selectedDecks != nil && selectedDecks[t.Name()] || selectedDecks == nil && t.Enabled() {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+// countryCards is a CardType rendering a single template per country.
+type countryCards struct {
+	name      string
+	dir       string // deck directory, defaults to name
+	tmpl      string
+	suffix    string                              // file name suffix, e.g. "_location"
+	enabled   func() bool                         // nil if always enabled
+	extract   func(c *Country, src *Source) error // optional
+	media     func(c *Country, src *Source) error // optional
+	has       func(c *Country) bool               // nil if every country has a card
+	aggregate func(countries []Country) error     // optional
+}
+
+func (t *countryCards) Name() string { return t.name }
+
+func (t *countryCards) Enabled() bool { return t.enabled == nil || t.enabled() }
+
+func (t *countryCards) deck() string {
+	if t.dir != "" {
+		return t.dir
+	}
+	return t.name
+}
+
+func (t *countryCards) Extract(c *Country, src *Source) error {
+	if t.extract == nil {
+		return nil
+	}
+	return t.extract(c, src)
+}
+
+func (t *countryCards) Render(c *Country, src *Source) error {
+	if t.has != nil && !t.has(c) {
+		return nil
+	}
+	if t.media != nil {
+		if err := t.media(c, src); err != nil {
+			return err
+		}
+	}
+	return makeCard(c, t.deck(), countryFileName(c)+t.suffix, t.tmpl)
+}
+
+func (t *countryCards) Aggregate(countries []Country) error {
+	if t.aggregate == nil {
+		return nil
+	}
+	return t.aggregate(countries)
+}
+
+// rootDeck is the directory of the location and map decks.
+const rootDeck = "."
+
+// makeMapImages writes the map images shared by the location and map decks,
+// once per country.
+func makeMapImages(c *Country, src *Source) error {
+	if !src.once("map images") {
+		return nil
+	}
+	if err := makeImage(deckDir(c, ""), c.MapName, src.Refresh); err != nil {
+		return err
+	}
+	if c.ZoomMapName != "" {
+		return makeImage(deckDir(c, ""), c.ZoomMapName, src.Refresh)
+	}
+	return nil
+}
+
+// readLocationAnswer loads the hand written location answer from the card.
+// Too difficult to parse automatically. The maps are merged per
+// --location-merge. Missing answers start as a skeleton to fill in.
+func readLocationAnswer(c *Country, src *Source) (string, error) {
+	ans, err := readAnswer(cardPath(c, "", countryFileName(c)+"_location", "location"))
+	if os.IsNotExist(err) && countryFileName(c) != c.UName {
+		// Answers written before --article=strip keep the page name.
+		ans, err = readAnswer(cardPath(c, "", c.UName+"_location", "location"))
+	}
+	if os.IsNotExist(err) && (*flagLayout != layoutFlat || *flagDifficulty == difficultyDirs) {
+		ans, err = readAnswer("countries", countryFileName(c)+"_location")
+	}
+	if os.IsNotExist(err) {
+		ans, err = locationSkeleton(c, src.Refresh)
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(ans, skeletonMarker) {
+		report.skeleton(c.UName)
+		dir, name := cardPath(c, "", countryFileName(c)+"_location", "location")
+		addFailureSite(failureSite{
+			Path:    filepath.Join(dir, cardName(name)+".md"),
+			Marker:  skeletonMarker,
+			Message: "location answer to write",
+		})
+	}
+	return mergeAnswer(*flagLocMerge, ans), nil
+}
+
+func init() {
+	registerCardType(&countryCards{
+		name:   "location",
+		dir:    rootDeck,
+		tmpl:   "location",
+		suffix: "_location",
+		extract: func(c *Country, src *Source) (err error) {
+			c.AnswerLocation, err = readLocationAnswer(c, src)
+			return err
+		},
+		media: makeMapImages,
+	})
+	registerCardType(&countryCards{
+		name:  "maps",
+		dir:   rootDeck,
+		tmpl:  "map",
+		media: makeMapImages,
+	})
+	registerCardType(&countryCards{
+		name: "flags",
+		tmpl: "flag",
+		media: func(c *Country, src *Source) error {
+			return makeFlagImage(deckDir(c, "flags"), c.FlagName, src.Refresh)
+		},
+	})
+	registerCardType(&countryCards{
+		name: "capitals",
+		tmpl: "capital",
+		extract: func(c *Country, src *Source) (err error) {
+			if *flagNative {
+				c.CapitalNative, err = getNativeCapital(c.UName, src.Refresh)
+			}
+			return err
+		},
+	})
+	registerCardType(&countryCards{
+		name:    "pronunciations",
+		tmpl:    "pronunciation",
+		enabled: func() bool { return *flagAudio },
+		extract: func(c *Country, src *Source) (err error) {
+			c.AudioName, err = getAudioName(c.Name, src.Refresh)
+			if c.AudioName != "" {
+				if err := sniffFile(c.AudioName, src.Refresh); err != nil {
+					return err
+				}
+				c.AudioURL = "audio/" + localName(c.AudioName)
+				c.Files = append(c.Files, filePageURL(c.AudioName))
+			}
+			return err
+		},
+		media: func(c *Country, src *Source) error {
+			return makeFile(filepath.Join(deckDir(c, "pronunciations"), "audio"), c.AudioName, src.Refresh)
+		},
+		has: func(c *Country) bool { return c.AudioURL != "" },
+	})
+	registerCardType(&countryCards{
+		name:    "highest",
+		tmpl:    "highest",
+		enabled: func() bool { return *flagHighest },
+		extract: func(c *Country, src *Source) (err error) {
+			c.HighestPoint, c.HighestElevation, err = getHighestPoint(c.UName, src.Refresh)
+			return err
+		},
+		has: func(c *Country) bool { return c.HighestPoint != "" },
+	})
+	registerCardType(&countryCards{
+		name:    "landlocked",
+		tmpl:    "landlocked",
+		enabled: func() bool { return *flagLandlocked },
+		extract: func(c *Country, src *Source) error {
+			return classifyGeography(c, c.UName, src.Refresh)
+		},
+		aggregate: makeRegionLists,
+	})
+	registerCardType(&countryCards{
+		name:    "timezones",
+		tmpl:    "timezone",
+		enabled: func() bool { return *flagTimeZones },
+		extract: func(c *Country, src *Source) error {
+			c.TimeZones = infoboxLines(src.Text(), "time_zone")
+			c.UTCOffset = cleanWikiText(infoboxField(src.Text(), "utc_offset"))
+			return nil
+		},
+		has: func(c *Country) bool { return len(c.TimeZones) > 0 },
+	})
+	registerCardType(&countryCards{
+		name:    "driving",
+		tmpl:    "driving",
+		enabled: func() bool { return *flagDriving },
+		extract: func(c *Country, src *Source) (err error) {
+			c.DrivesOn = parseDrivesOn(infoboxField(src.Text(), "drives_on"))
+			if c.Continents == nil { // the summary card groups by continent
+				c.Continents, err = getContinents(c.UName, src.Refresh)
+			}
+			return err
+		},
+		has:       func(c *Country) bool { return c.DrivesOn != "" },
+		aggregate: makeLeftDriving,
+	})
+	registerCardType(&countryCards{
+		name:    "independence",
+		tmpl:    "independence",
+		enabled: func() bool { return *flagIndependence },
+		extract: func(c *Country, src *Source) error {
+			c.Independence = parseIndependence(src.Text(), *flagDateFormat == "year")
+			return nil
+		},
+		has: func(c *Country) bool { return c.Independence != "" },
+	})
+	registerCardType(&countryCards{
+		name:    "national_days",
+		tmpl:    "national-day",
+		enabled: func() bool { return *flagIndependence },
+		extract: func(c *Country, src *Source) (err error) {
+			c.NationalDay, err = getNationalDay(c.UName, src.Refresh)
+			return err
+		},
+		has: func(c *Country) bool { return c.NationalDay != "" },
+	})
+	mottoExtract := func(c *Country, src *Source) error {
+		c.Motto, c.MottoTranslation = parseMotto(src.Text())
+		return nil
+	}
+	registerCardType(&countryCards{
+		name:    "mottos",
+		tmpl:    "motto",
+		enabled: func() bool { return *flagMotto },
+		extract: mottoExtract,
+		has:     func(c *Country) bool { return c.Motto != "" },
+	})
+	registerCardType(&countryCards{
+		name:    "mottos_reverse",
+		dir:     "mottos",
+		tmpl:    "motto-reverse",
+		suffix:  "_reverse",
+		enabled: func() bool { return *flagMotto },
+		extract: mottoExtract,
+		has:     func(c *Country) bool { return c.Motto != "" },
+	})
+	registerCardType(&countryCards{
+		name:    "superlatives",
+		enabled: func() bool { return *flagSuperlatives },
+		extract: func(c *Country, src *Source) error {
+			return getStats(c, c.UName, src.Refresh)
+		},
+		has:       func(c *Country) bool { return false }, // aggregate only
+		aggregate: makeSuperlatives,
+	})
+	registerCardType(&countryCards{
+		name:    "currencies",
+		enabled: func() bool { return *flagCurrencies },
+		extract: func(c *Country, src *Source) (err error) {
+			c.Currencies, err = getCurrencies(c.UName, src.Refresh)
+			return err
+		},
+		has:       func(c *Country) bool { return false }, // aggregate only
+		aggregate: makeCurrencyLists,
+	})
+	registerCardType(&countryCards{
+		name:    "currency_symbols",
+		enabled: func() bool { return *flagCurrencySym },
+		extract: func(c *Country, src *Source) (err error) {
+			if c.Currencies == nil {
+				c.Currencies, err = getCurrencies(c.UName, src.Refresh)
+			}
+			return err
+		},
+		has:       func(c *Country) bool { return false }, // aggregate only
+		aggregate: makeCurrencySymbols,
+	})
+	registerCardType(&countryCards{
+		name:    "waters",
+		tmpl:    "waters",
+		enabled: func() bool { return *flagWaters },
+		extract: func(c *Country, src *Source) (err error) {
+			c.Waters, err = getWaters(c.UName, src.Refresh)
+			return err
+		},
+		has: func(c *Country) bool { return len(c.Waters) > 0 },
+	})
+	registerCardType(&countryCards{
+		name:    "borders",
+		tmpl:    "borders",
+		enabled: func() bool { return *flagBorders },
+		extract: func(c *Country, src *Source) error {
+			if c.Neighbors != nil {
+				return nil
+			}
+			return getStats(c, c.UName, src.Refresh)
+		},
+		has: func(c *Country) bool { return len(c.Neighbors) > 0 },
+		aggregate: func(countries []Country) error {
+			return makeSuperlative(filepath.Join("countries", "borders"), mostNeighbors, countries)
+		},
+	})
+	registerCardType(&countryCards{
+		name:    "distances",
+		enabled: func() bool { return *flagDistances },
+		extract: func(c *Country, src *Source) (err error) {
+			c.CapitalLocation, err = getCapitalLocation(c.UName, src.Refresh)
+			return err
+		},
+		has:       func(c *Country) bool { return false }, // aggregate only
+		aggregate: makeDistanceCards,
+	})
+	registerCardType(&countryCards{
+		name:    "populations",
+		tmpl:    "population",
+		enabled: func() bool { return *flagPopBuckets != "" },
+		extract: extractPopulationBucket,
+		has:     func(c *Country) bool { return c.PopulationBucket != "" },
+	})
+	registerCardType(&countryCards{
+		name:    "gdp",
+		dir:     "economy",
+		tmpl:    "gdp",
+		suffix:  "_gdp",
+		enabled: func() bool { return *flagEconomy },
+		extract: extractEconomy,
+		has:     func(c *Country) bool { return c.GDP > 0 },
+	})
+	registerCardType(&countryCards{
+		name:    "income",
+		dir:     "economy",
+		tmpl:    "income",
+		suffix:  "_income",
+		enabled: func() bool { return *flagEconomy },
+		extract: extractEconomy,
+		has:     func(c *Country) bool { return c.IncomeGroup != "" },
+	})
+	registerCardType(&countryCards{
+		name:    "industries",
+		dir:     "economy",
+		tmpl:    "industries",
+		suffix:  "_industries",
+		enabled: func() bool { return *flagEconomy },
+		extract: extractEconomy,
+		has:     func(c *Country) bool { return len(c.Industries) > 0 },
+	})
+	registerCardType(&countryCards{
+		name:    "hdi",
+		tmpl:    "hdi",
+		enabled: func() bool { return *flagHDI },
+		extract: extractHDI,
+		has:     func(c *Country) bool { return c.HDITier != "" },
+	})
+	registerCardType(&countryCards{
+		name:    "religions",
+		tmpl:    "religion",
+		enabled: func() bool { return *flagSensitive },
+		extract: extractDemographics,
+		has:     func(c *Country) bool { return len(c.Religions) > 0 },
+	})
+	registerCardType(&countryCards{
+		name:    "ethnicities",
+		tmpl:    "ethnicity",
+		enabled: func() bool { return *flagSensitive },
+		extract: extractDemographics,
+		has:     func(c *Country) bool { return len(c.EthnicGroups) > 0 },
+	})
+	registerCardType(&countryCards{
+		name:    "former_capitals",
+		dir:     "capital_moves",
+		tmpl:    "former-capital",
+		suffix:  "_former",
+		enabled: func() bool { return *flagCapitalMoves },
+		extract: extractCapitalMove,
+		has:     func(c *Country) bool { return c.CapitalMove != nil },
+	})
+	registerCardType(&countryCards{
+		name:    "capital_moves",
+		tmpl:    "capital-move",
+		suffix:  "_moved",
+		enabled: func() bool { return *flagCapitalMoves },
+		extract: extractCapitalMove,
+		has:     func(c *Country) bool { return c.CapitalMove != nil },
+	})
+	registerCardType(&countryCards{
+		name:    "largest_cities",
+		tmpl:    "largest-city",
+		suffix:  "_largest",
+		enabled: func() bool { return *flagLargestCity },
+		extract: extractLargestCity,
+		has:     func(c *Country) bool { return c.LargestCity != "" },
+	})
+	registerCardType(&countryCards{
+		name:      "government",
+		tmpl:      "government",
+		enabled:   func() bool { return *flagGovernment },
+		extract:   extractGovernment,
+		has:       func(c *Country) bool { return c.Government != "" },
+		aggregate: makeMonarchyLists,
+	})
+	registerCardType(&countryCards{
+		name:    "emoji_clues",
+		tmpl:    "emoji",
+		suffix:  "_emoji",
+		enabled: func() bool { return *flagEmojiClues },
+		extract: extractEmojiClue,
+		has:     func(c *Country) bool { return c.EmojiClue != nil },
+	})
+	registerCardType(&countryCards{
+		name:    "subregions",
+		tmpl:    "subregion",
+		enabled: func() bool { return *flagSubregions },
+		extract: extractSubregion,
+		has:     func(c *Country) bool { return c.Subregion != "" },
+	})
+	registerCardType(&countryCards{
+		name:      "flag_features",
+		enabled:   func() bool { return *flagFlagFeature },
+		has:       func(c *Country) bool { return false }, // aggregate only
+		aggregate: makeFlagFeatures,
+	})
+	registerCardType(&countryCards{
+		name:    "profiles",
+		tmpl:    "profile",
+		enabled: func() bool { return *flagProfile },
+		extract: extractProfile,
+		media: func(c *Country, src *Source) error {
+			return makeImage(deckDir(c, "profiles"), c.FlagName, src.Refresh)
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"io"
+	"testing"
+)
+
+// countingFetcher counts the files fetched.
+type countingFetcher struct {
+	Fetcher
+	files map[string]int
+}
+
+func (f *countingFetcher) File(name string, refresh bool) (io.ReadCloser, error) {
+	f.files[name]++
+	return f.Fetcher.File(name, refresh)
+}
+
+func TestMapImagesOnce(t *testing.T) {
+	f := &countingFetcher{
+		Fetcher: &memFetcher{Files: map[string][]byte{"Ghana.svg": []byte(pngHeader)}},
+		files:   make(map[string]int),
+	}
+	withFetcher(t, f)
+	c := &Country{Name: "Ghana", UName: "Ghana", MapName: "Ghana.svg"}
+	src := &Source{}
+	for _, name := range []string{"location", "maps"} {
+		for _, ct := range cardTypes {
+			if ct.Name() == name {
+				if err := ct.(*countryCards).media(c, src); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	if n := f.files["Ghana.svg"]; n != 1 {
+		t.Errorf("map fetched %d times, want 1", n)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+)
+
+// Size buckets by area in km².
+const (
+	microstateArea = 1000
+	smallArea      = 100000
+	largeArea      = 1000000
+)
+
+// sizeTag returns the size bucket of the country.
+func sizeTag(c *Country) string {
+	switch {
+	case microstates[c.UName] || c.Area < microstateArea:
+		return "microstate"
+	case c.Area < smallArea:
+		return "small"
+	case c.Area < largeArea:
+		return "medium"
+	default:
+		return "large"
+	}
+}
+
+// Difficulty tiers, set by --difficulty as tags or sub-deck directories.
+const (
+	difficultyTags = "tags"
+	difficultyDirs = "dirs"
+)
+
+// Population thresholds of the difficulty tiers. Around 50 countries have
+// over 30 million people, which are mostly well known.
+const (
+	beginnerPopulation     = 30000000
+	intermediatePopulation = 5000000
+)
+
+// difficultyTier returns the difficulty of learning the country, well known
+// countries are assumed to be the populous ones.
+func difficultyTier(c *Country) string {
+	switch {
+	case c.Population >= beginnerPopulation:
+		return "beginner"
+	case c.Population >= intermediatePopulation:
+		return "intermediate"
+	default:
+		return "advanced"
+	}
+}
+
+// classify computes the tags of the country after extraction: region, size
+// bucket, landlocked, island and difficulty. They are only computed when
+// filtering by tags or tiering by difficulty as they need extra requests.
+func classify(c *Country, refresh bool) error {
+	if *flagTags == "" && *flagDifficulty == "" {
+		return nil
+	}
+	if !*flagLandlocked {
+		if err := classifyGeography(c, c.UName, refresh); err != nil {
+			return err
+		}
+	}
+	if c.Area == 0 && c.Population == 0 {
+		if err := getStats(c, c.UName, refresh); err != nil {
+			return err
+		}
+	}
+	for _, continent := range c.Continents {
+		c.Tags = appendTag(c.Tags, slug(continent))
+	}
+	if c.Area > 0 {
+		c.Tags = appendTag(c.Tags, sizeTag(c))
+	}
+	if *flagDifficulty != "" {
+		c.Difficulty = difficultyTier(c)
+		c.Tags = appendTag(c.Tags, c.Difficulty)
+	}
+	return nil
+}
+
+func appendTag(tags []string, tag string) []string {
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
+
+// matchTags reports whether the country has any of the --tags tags.
+func matchTags(c *Country) bool {
+	if *flagTags == "" {
+		return true
+	}
+	for _, v := range strings.Split(*flagTags, ",") {
+		v = strings.TrimSpace(v)
+		for _, t := range c.Tags {
+			if t == v {
+				return true
+			}
+		}
+	}
+	return false
+}
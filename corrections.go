@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Correction is a user supplied fix of an extracted field, read from the
+// --apply-corrections csv.
+type Correction struct {
+	Country string // name as in countries.txt
+	Field   string // Country field, e.g. Capital
+	Value   string // lists are separated by semicolons
+	Reason  string
+}
+
+// corrections by country name.
+var corrections map[string][]Correction
+
+// correctionAliases are the override field names accepted as corrections.
+var correctionAliases = map[string]string{
+	"map":  "MapName",
+	"flag": "FlagName",
+}
+
+// correctionField returns the Country field named case insensitively,
+// ignoring underscores, e.g. highest_point -> HighestPoint.
+func correctionField(name string) (reflect.StructField, bool) {
+	if alias, ok := correctionAliases[strings.ToLower(name)]; ok {
+		name = alias
+	}
+	key := strings.ToLower(strings.Replace(name, "_", "", -1))
+	return reflect.TypeOf(Country{}).FieldByNameFunc(func(f string) bool {
+		return strings.ToLower(f) == key
+	})
+}
+
+// parseCorrection converts the value to the field's type.
+func parseCorrection(t reflect.Type, s string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(f)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.String {
+			return v, fmt.Errorf("unsupported type %s", t)
+		}
+		v.Set(reflect.ValueOf(splitList(strings.Replace(s, ";", ",", -1))))
+	default:
+		return v, fmt.Errorf("unsupported type %s", t)
+	}
+	return v, nil
+}
+
+// readCorrections parses the csv of "country,field,value,reason" rows, an
+// optional header row is skipped. Fields and values are validated up front
+// so a bad row fails before any fetching.
+func readCorrections(path string) (map[string][]Correction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 4
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	m := make(map[string][]Correction)
+	for i, row := range rows {
+		if i == 0 && strings.EqualFold(row[0], "country") {
+			continue
+		}
+		c := Correction{Country: row[0], Field: row[1], Value: row[2], Reason: row[3]}
+		sf, ok := correctionField(c.Field)
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: unknown field %q", path, i+1, c.Field)
+		}
+		if _, err := parseCorrection(sf.Type, c.Value); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %w", path, i+1, c.Field, err)
+		}
+		m[c.Country] = append(m[c.Country], c)
+	}
+	return m, nil
+}
+
+// applyCorrections sets the corrected fields of the country, recording them
+// in the provenance footer and the report. Corrected images are re-sniffed
+// so cards reference them.
+func applyCorrections(c *Country, refresh bool) error {
+	for _, x := range corrections[c.Name] {
+		sf, _ := correctionField(x.Field)
+		v, err := parseCorrection(sf.Type, x.Value)
+		if err != nil {
+			return err
+		}
+		reflect.ValueOf(c).Elem().FieldByIndex(sf.Index).Set(v)
+		switch sf.Name {
+		case "MapName", "FlagName":
+			if !*flagRemoteImages {
+				if err := sniffFile(x.Value, refresh); err != nil {
+					return err
+				}
+			}
+			if sf.Name == "MapName" {
+				c.MapImageURL = imageURL(x.Value)
+			} else {
+				c.FlagImageURL = imageURL(x.Value)
+			}
+			c.Files = append(c.Files, filePageURL(x.Value))
+		}
+		note := sf.Name + " = " + x.Value
+		if x.Reason != "" {
+			note += " (" + x.Reason + ")"
+		}
+		c.Corrections = append(c.Corrections, note)
+		report.override(c.UName, sf.Name)
+	}
+	return nil
+}
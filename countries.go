@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// readCountryList loads the previous country list, empty if missing.
+func readCountryList(path string) ([]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range strings.Split(string(b), "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// diffCountryLists returns the names added and removed from old to new.
+func diffCountryLists(old, new []string) (added, removed []string) {
+	inOld := make(map[string]bool, len(old))
+	for _, name := range old {
+		inOld[name] = true
+	}
+	inNew := make(map[string]bool, len(new))
+	for _, name := range new {
+		inNew[name] = true
+		if !inOld[name] {
+			added = append(added, name)
+		}
+	}
+	for _, name := range old {
+		if !inNew[name] {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
+
+func formatDiff(added, removed []string) string {
+	var b strings.Builder
+	for _, name := range removed {
+		fmt.Fprintf(&b, "- %s\n", name)
+	}
+	for _, name := range added {
+		fmt.Fprintf(&b, "+ %s\n", name)
+	}
+	return b.String()
+}
+
+// checkCountryCount fails if the scraped list doesn't have the expected
+// number of countries, showing the diff against the previous list.
+func checkCountryCount(countries []string, expect int, path string) error {
+	if expect <= 0 || len(countries) == expect {
+		return nil
+	}
+	old, err := readCountryList(path)
+	if err != nil {
+		return err
+	}
+	added, removed := diffCountryLists(old, countries)
+	return fmt.Errorf("expected %d countries, found %d; has the list page format changed?\n%s",
+		expect, len(countries), formatDiff(added, removed))
+}
+
+// checkCountryList treats the checked in list as a contract: the scraped
+// list must match it unless accept is set, in which case the list is
+// updated. A missing list is written.
+func checkCountryList(countries []string, path string, accept bool) error {
+	old, err := readCountryList(path)
+	if err != nil {
+		return err
+	}
+	added, removed := diffCountryLists(old, countries)
+	if old != nil && len(added)+len(removed) > 0 && !accept {
+		return fmt.Errorf("country list differs from %s, rerun with --accept-list-changes if expected:\n%s",
+			path, formatDiff(added, removed))
+	}
+	return writeOutput(path, []byte(strings.Join(countries, "\n")))
+}
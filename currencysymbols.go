@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const currencySymbolsFile = "currency_symbols.txt"
+
+// CurrencySymbol is a currency recognisable by its symbol, with the
+// generated countries using it.
+type CurrencySymbol struct {
+	Currency  string
+	Symbol    string
+	Countries []string
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("currency-symbol").Parse(`Which currency has the symbol **{{.Symbol}}**?
+<!--question-->
+**{{.Currency}}**
+
+*Used by {{range $i, $v := .Countries}}{{if $i}}, {{end}}{{$v}}{{end}}*`))
+}
+
+// readCurrencySymbols parses the bundled table, one currency per line as
+// "currency | symbol", skipping blank lines and # comments.
+func readCurrencySymbols(path string) ([]CurrencySymbol, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var symbols []CurrencySymbol
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want 2 fields, got %d", path, i+1, len(fields))
+		}
+		symbols = append(symbols, CurrencySymbol{
+			Currency: strings.TrimSpace(fields[0]),
+			Symbol:   strings.TrimSpace(fields[1]),
+		})
+	}
+	return symbols, nil
+}
+
+// makeCurrencySymbols renders a card per symbol of a currency used by the
+// generated countries, currencies match case insensitively.
+func makeCurrencySymbols(countries []Country) error {
+	symbols, err := readCurrencySymbols(currencySymbolsFile)
+	if err != nil {
+		return err
+	}
+	users := make(map[string][]string)
+	for _, c := range countries {
+		for _, currency := range c.Currencies {
+			key := strings.ToLower(currency)
+			users[key] = append(users[key], c.Name)
+		}
+	}
+
+	dir := filepath.Join("countries", "currencies", "symbols")
+	for _, s := range symbols {
+		s.Countries = users[strings.ToLower(s.Currency)]
+		if len(s.Countries) == 0 {
+			continue
+		}
+		sort.Strings(s.Countries)
+		if err := makeTmpl(dir, toURLName(s.Currency), "currency-symbol", &s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
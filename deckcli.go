@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// deckManifest is the deck.json written in each deck directory for the deck
+// flashcard tool: the deck name, its media folder and stable card IDs.
+type deckManifest struct {
+	Name  string     `json:"name"`
+	Media string     `json:"media,omitempty"` // folder relative to the manifest
+	Cards []deckCard `json:"cards"`
+}
+
+type deckCard struct {
+	ID   string `json:"id"`
+	File string `json:"file"`
+}
+
+// cardID is stable across runs so study history survives regeneration.
+func cardID(deck, name string) string {
+	h := sha1.Sum([]byte(filepath.ToSlash(filepath.Join(deck, name))))
+	return hex.EncodeToString(h[:8])
+}
+
+// writeDeckManifests writes a deck.json in every directory under root with
+// cards.
+func writeDeckManifests(root string) error {
+	decks := make(map[string][]string)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Ext(p) == ".md" && info.Name() != indexFile {
+			dir := filepath.Dir(p)
+			decks[dir] = append(decks[dir], info.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for dir, files := range decks {
+		deck, err := filepath.Rel(root, dir)
+		if err != nil {
+			return err
+		}
+		sort.Strings(files)
+		m := deckManifest{Name: deckFileName(deck)}
+		if info, err := os.Stat(filepath.Join(dir, "images")); err == nil && info.IsDir() {
+			m.Media = "images"
+		}
+		for _, f := range files {
+			name := strings.TrimSuffix(f, ".md")
+			m.Cards = append(m.Cards, deckCard{ID: cardID(deck, name), File: f})
+		}
+		b, err := json.MarshalIndent(&m, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeOutput(filepath.Join(dir, "deck.json"), append(b, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncDecks writes the deck manifests then runs the --deck-hook import
+// command, with DECK_ROOT set to the decks directory.
+func syncDecks(root string) error {
+	if err := writeDeckManifests(root); err != nil {
+		return err
+	}
+	if *flagDeckHook == "" || *flagCheck {
+		return nil
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("sh", "-c", *flagDeckHook)
+	cmd.Env = append(os.Environ(), "DECK_ROOT="+abs)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// maxShares caps the groups listed on a card.
+const maxShares = 5
+
+// 63.5%, 12 %
+var rePercent = regexp.MustCompile(`([0-9]+(?:\.[0-9]+)?)\s*%`)
+
+// Share is a group's percentage of the population.
+type Share struct {
+	Name    string
+	Percent float64
+}
+
+// RoundedPercent formats the percentage to a whole number, e.g. "63%" or
+// "<1%".
+func (s Share) RoundedPercent() string {
+	if s.Percent < 0.5 {
+		return "<1%"
+	}
+	return strconv.Itoa(int(math.Round(s.Percent))) + "%"
+}
+
+// parseShares returns the groups with a percentage listed in the infobox
+// field, e.g. "63.0% Christianity". Nested sub-groups would count twice, so
+// groups passing a total of 100% are skipped.
+func parseShares(text, field string) []Share {
+	var shares []Share
+	var total float64
+	for _, line := range infoboxLines(text, field) {
+		loc := rePercent.FindStringSubmatchIndex(line)
+		if loc == nil {
+			continue
+		}
+		percent, err := strconv.ParseFloat(line[loc[2]:loc[3]], 64)
+		if err != nil {
+			continue
+		}
+		if total+percent > 100.5 {
+			continue
+		}
+		total += percent
+		name := strings.Trim(line[:loc[0]]+" "+line[loc[1]:], " –—-:,()")
+		if name == "" {
+			continue
+		}
+		shares = append(shares, Share{Name: name, Percent: percent})
+		if len(shares) == maxShares {
+			break
+		}
+	}
+	return shares
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("religion").Funcs(template.FuncMap{
+		"sensitive": func(tags []string) []string {
+			return append(append([]string(nil), tags...), "sensitive")
+		},
+	}).Parse(`{{template "front-matter" front nil (sensitive .Tags)}}What are the main religions of **{{.Name}}**?
+<!--question-->
+{{range .Religions}}- {{.Name}} *({{.RoundedPercent}})*
+{{end}}`))
+	tmpls = template.Must(tmpls.New("ethnicity").Parse(`{{template "front-matter" front nil (sensitive .Tags)}}What are the main ethnic groups of **{{.Name}}**?
+<!--question-->
+{{range .EthnicGroups}}- {{.Name}} *({{.RoundedPercent}})*
+{{end}}`))
+}
+
+// extractDemographics sets the religions and ethnic groups from the infobox.
+func extractDemographics(c *Country, src *Source) error {
+	c.Religions = parseShares(src.Text(), "religion")
+	c.EthnicGroups = parseShares(src.Text(), "ethnic_groups")
+	return nil
+}
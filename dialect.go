@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// questionTag separates the question from the answer in the templates, it
+// is rewritten for the output dialect.
+const questionTag = "<!--question-->"
+
+var (
+	// ![alt](url), urls may contain balanced parentheses.
+	reMarkdownImage = regexp.MustCompile(`!\[([^\]]*)\]\(((?:[^()\s]|\([^()\s]*\))+)\)`)
+
+	// **bold**
+	reMarkdownBold = regexp.MustCompile(`\*\*([^*\n]+)\*\*`)
+
+	// *italic*
+	reMarkdownItalic = regexp.MustCompile(`\*([^*\n]+)\*`)
+)
+
+// Dialect formats cards for a flashcard tool.
+type Dialect struct {
+	Name string
+	// Render joins the question and answer of a card.
+	Render func(question, answer string) string
+	// Split returns the question and answer of a rendered card.
+	Split func(card string) (question, answer string, ok bool)
+}
+
+// splitOn splits at the last separator, so front matter delimiters before the
+// question are skipped.
+func splitOn(sep string) func(string) (string, string, bool) {
+	return func(card string) (string, string, bool) {
+		i := strings.LastIndex(card, sep)
+		if i < 0 {
+			return "", "", false
+		}
+		return card[:i], card[i+len(sep):], true
+	}
+}
+
+const obsidianCallout = "> [!answer]-\n"
+
+var dialects = []Dialect{{
+	Name: "markdown",
+	Render: func(q, a string) string {
+		return q + questionTag + a
+	},
+	Split: splitOn(questionTag),
+}, {
+	// A thematic break, blank lines avoid a setext heading.
+	Name: "dashes",
+	Render: func(q, a string) string {
+		return q + "\n---\n" + a
+	},
+	Split: splitOn("\n---\n"),
+}, {
+	// The answer in a folded Obsidian callout.
+	Name: "obsidian",
+	Render: func(q, a string) string {
+		lines := strings.Split(strings.TrimLeft(a, "\n"), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return q + "\n" + obsidianCallout + strings.Join(lines, "\n")
+	},
+	Split: func(card string) (string, string, bool) {
+		i := strings.LastIndex(card, obsidianCallout)
+		if i < 0 {
+			return "", "", false
+		}
+		var lines []string
+		for _, line := range strings.Split(card[i+len(obsidianCallout):], "\n") {
+			if !strings.HasPrefix(line, ">") {
+				break
+			}
+			lines = append(lines, strings.TrimPrefix(strings.TrimPrefix(line, ">"), " "))
+		}
+		return card[:i], strings.Join(lines, "\n"), true
+	},
+}, {
+	// Anki HTML with its answer separator.
+	Name: "anki",
+	Render: func(q, a string) string {
+		return ankiHTML(strings.TrimSpace(q)) + "\n<hr id=answer>\n" + ankiHTML(strings.TrimSpace(a))
+	},
+	Split: splitOn("<hr id=answer>"),
+}}
+
+func ankiHTML(s string) string {
+	s = reMarkdownImage.ReplaceAllString(s, `<img src="$2" alt="$1">`)
+	s = reMarkdownBold.ReplaceAllString(s, "<b>$1</b>")
+	s = reMarkdownItalic.ReplaceAllString(s, "<i>$1</i>")
+	return strings.Replace(s, "\n", "<br>\n", -1)
+}
+
+// dialect returns the selected --dialect.
+func dialect() (*Dialect, error) {
+	for i := range dialects {
+		if dialects[i].Name == *flagDialect {
+			return &dialects[i], nil
+		}
+	}
+	return nil, fmt.Errorf("invalid dialect %q", *flagDialect)
+}
+
+// applyDialect rewrites the question separator of a rendered card, any front
+// matter is kept as is.
+func applyDialect(card string) (string, error) {
+	d, err := dialect()
+	if err != nil {
+		return "", err
+	}
+	i := strings.Index(card, questionTag)
+	if i < 0 {
+		return card, nil
+	}
+	var front string
+	if strings.HasPrefix(card, "---\n") {
+		if j := strings.Index(card[4:], "\n---\n"); j > -1 && 4+j < i {
+			front, card = card[:4+j+5], card[4+j+5:]
+			i -= len(front)
+		}
+	}
+	return front + d.Render(card[:i], card[i+len(questionTag):]), nil
+}
+
+// splitCard returns the question and answer of a card in the selected
+// dialect, falling back to markdown for cards written before a dialect
+// change.
+func splitCard(card string) (question, answer string, ok bool) {
+	if d, err := dialect(); err == nil {
+		if q, a, ok := d.Split(card); ok {
+			return q, a, true
+		}
+	}
+	return dialects[0].Split(card)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"text/template"
+)
+
+// CapitalCompare asks which of two capitals is farther north.
+type CapitalCompare struct {
+	A, B   *Country
+	Answer *Country
+}
+
+// CapitalPair is the closest pair of capitals on a continent.
+type CapitalPair struct {
+	Continent string
+	A, B      *Country
+	Distance  string
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("capital-north").Parse(`Which capital is farther north, **{{.A.Capital}}** or **{{.B.Capital}}**?
+<!--question-->
+**{{.Answer.Capital}}** ({{.Answer.Name}})
+
+{{.A.Capital}}: {{lat .A.CapitalLocation.Lat}}, {{.B.Capital}}: {{lat .B.CapitalLocation.Lat}}`))
+	tmpls = template.Must(tmpls.New("capital-closest").Parse(`Which two capitals in **{{.Continent}}** are closest together?
+<!--question-->
+**{{.A.Capital}}** ({{.A.Name}}) and **{{.B.Capital}}** ({{.B.Name}}), {{.Distance}} apart`))
+}
+
+// formatLat formats a latitude in degrees north or south, e.g. 59.9°N.
+func formatLat(lat float64) string {
+	if lat < 0 {
+		return fmt.Sprintf("%.1f°S", -lat)
+	}
+	return fmt.Sprintf("%.1f°N", lat)
+}
+
+// getCapitalLocation returns the coordinates of the country's capital.
+func getCapitalLocation(uname string, refresh bool) (*LatLon, error) {
+	e, err := getCapitalEntity(uname, refresh)
+	if err != nil || e == nil {
+		return nil, err
+	}
+	vs := e.Coordinates("P625") // coordinate location
+	if len(vs) == 0 {
+		return nil, nil
+	}
+	return &vs[0], nil
+}
+
+// makeDistanceCards compares each capital with its nearest capital and
+// renders the closest pair of capitals per continent.
+func makeDistanceCards(countries []Country) error {
+	var located []*Country
+	for i := range countries {
+		if countries[i].CapitalLocation != nil {
+			located = append(located, &countries[i])
+		}
+	}
+	dir := filepath.Join("countries", "distances")
+
+	done := make(map[[2]string]bool)
+	for _, a := range located {
+		var nearest *Country
+		min := math.Inf(1)
+		for _, b := range located {
+			if b == a {
+				continue
+			}
+			if d := distance(*a.CapitalLocation, *b.CapitalLocation); d < min {
+				nearest, min = b, d
+			}
+		}
+		if nearest == nil || nearest.CapitalLocation.Lat == a.CapitalLocation.Lat {
+			continue
+		}
+		key := [2]string{a.UName, nearest.UName}
+		if key[0] > key[1] {
+			key[0], key[1] = key[1], key[0]
+		}
+		if done[key] {
+			continue
+		}
+		done[key] = true
+
+		cmp := CapitalCompare{A: a, B: nearest, Answer: a}
+		if nearest.CapitalLocation.Lat > a.CapitalLocation.Lat {
+			cmp.Answer = nearest
+		}
+		if err := makeTmpl(dir, key[0]+"_"+key[1]+"_north", "capital-north", &cmp); err != nil {
+			return err
+		}
+	}
+
+	byContinent := make(map[string][]*Country)
+	for _, c := range located {
+		if len(c.Continents) > 0 {
+			byContinent[c.Continents[0]] = append(byContinent[c.Continents[0]], c)
+		}
+	}
+	continents := make([]string, 0, len(byContinent))
+	for k := range byContinent {
+		continents = append(continents, k)
+	}
+	sort.Strings(continents)
+	for _, continent := range continents {
+		cs := byContinent[continent]
+		if len(cs) < 2 {
+			continue
+		}
+		var pair CapitalPair
+		min := math.Inf(1)
+		for i := range cs {
+			for j := i + 1; j < len(cs); j++ {
+				if d := distance(*cs[i].CapitalLocation, *cs[j].CapitalLocation); d < min {
+					min = d
+					pair = CapitalPair{Continent: continent, A: cs[i], B: cs[j]}
+				}
+			}
+		}
+		pair.Distance = formatLength(min)
+		if err := makeTmpl(dir, "closest_"+toURLName(continent), "capital-closest", &pair); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"text/template"
+)
+
+// maxIndustries caps the industries listed on a card.
+const maxIndustries = 5
+
+// Income groups by GDP per capita in US dollars, approximating the World
+// Bank thresholds which use GNI.
+var incomeGroups = []struct {
+	min  float64
+	name string
+}{
+	{13845, "high"},
+	{4466, "upper-middle"},
+	{1136, "lower-middle"},
+	{0, "low"},
+}
+
+func incomeGroup(perCapita float64) string {
+	for _, g := range incomeGroups {
+		if perCapita >= g.min {
+			return g.name
+		}
+	}
+	return ""
+}
+
+// moneyUnits abbreviate US dollar amounts.
+var moneyUnits = []scaleUnit{{1e12, "trillion"}, {1e9, "billion"}, {1e6, "million"}}
+
+// formatMoney formats US dollars to three significant figures, e.g.
+// 2782905000000 -> "$2.78 trillion".
+func formatMoney(n float64) string {
+	if s, ok := formatScaled(n, moneyUnits); ok {
+		return "$" + s
+	}
+	return "$" + formatInt(n)
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("gdp").Parse(`{{template "front-matter" front nil .Tags}}What is the nominal GDP of **{{.Name}}**?
+<!--question-->
+**{{usd .GDP}}**{{if .GDPYear}} *({{.GDPYear}})*{{end}}`))
+	tmpls = template.Must(tmpls.New("income").Parse(`{{template "front-matter" front nil .Tags}}Is the GDP per capita of **{{.Name}}** low, lower-middle, upper-middle or high income?
+<!--question-->
+**{{.IncomeGroup}}** *({{usd .GDPPerCapita}}{{if .GDPPerCapitaYear}}, {{.GDPPerCapitaYear}}{{end}})*`))
+	tmpls = template.Must(tmpls.New("industries").Parse(`{{template "front-matter" front nil .Tags}}What are the main industries of **{{.Name}}**?
+<!--question-->
+{{range .Industries}}- {{.}}
+{{end}}`))
+}
+
+// getIndustries returns the main industries from the infobox of the
+// country's economy page, nil if there is no such page.
+func getIndustries(uname string, refresh bool) ([]string, error) {
+	page, err := getLockedWikiPage(wikipedia, "Economy_of_"+uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	lines := infoboxLines(page.Revisions[0].Text, "industries")
+	if len(lines) == 1 {
+		lines = strings.Split(lines[0], ",")
+	}
+	var industries []string
+	for _, v := range lines {
+		v = strings.TrimSpace(strings.TrimSuffix(v, "."))
+		if v == "" {
+			continue
+		}
+		industries = append(industries, strings.ToUpper(v[:1])+v[1:])
+		if len(industries) == maxIndustries {
+			break
+		}
+	}
+	return industries, nil
+}
+
+// extractEconomy sets the latest GDP figures and their years from wikidata
+// and the industries from the economy page.
+func extractEconomy(c *Country, src *Source) error {
+	if !src.once("economy") {
+		return nil // shared by the economy decks
+	}
+	e, err := getEntity(c.UName, src.Refresh)
+	if err != nil {
+		return err
+	}
+	c.GDP, c.GDPYear = e.LatestQuantity("P2131")                   // nominal GDP
+	c.GDPPerCapita, c.GDPPerCapitaYear = e.LatestQuantity("P2132") // nominal GDP per capita
+	if c.GDPPerCapita > 0 {
+		c.IncomeGroup = incomeGroup(c.GDPPerCapita)
+	}
+	if c.Industries, err = getIndustries(c.UName, src.Refresh); err != nil {
+		// Not every country has an economy page.
+		report.warnf(c.UName, "industries: %v", err)
+		report.fallback(c.UName, "industries")
+	}
+	return nil
+}
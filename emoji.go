@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+const emojiCluesFile = "emoji_clues.txt"
+
+// EmojiClue is a curated emoji rebus of the country's name.
+type EmojiClue struct {
+	Clue string
+	Hint string // may be empty
+}
+
+// emojiClues by country name, read from emoji_clues.txt with --emoji-clues.
+var emojiClues map[string]EmojiClue
+
+// readEmojiClues parses the curated clues, one per line as "country | clue |
+// hint", skipping blank lines and # comments.
+func readEmojiClues(path string) (map[string]EmojiClue, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	clues := make(map[string]EmojiClue)
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: want 3 fields, got %d", path, i+1, len(fields))
+		}
+		clues[strings.TrimSpace(fields[0])] = EmojiClue{
+			Clue: strings.TrimSpace(fields[1]),
+			Hint: strings.TrimSpace(fields[2]),
+		}
+	}
+	return clues, nil
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("emoji").Parse(`{{template "front-matter" front .AltNames .Tags}}Which country is this emoji clue?
+
+# {{.EmojiClue.Clue}}
+{{with .EmojiClue.Hint}}
+*Hint: {{.}}*
+{{end}}<!--question-->
+**{{.Name}}**`))
+}
+
+// extractEmojiClue merges the curated clue into the country.
+func extractEmojiClue(c *Country, src *Source) error {
+	if clue, ok := emojiClues[c.Name]; ok {
+		c.EmojiClue = &clue
+	}
+	return nil
+}
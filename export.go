@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Card is a generated card read back for export to other flashcard tools.
+type Card struct {
+	Name     string // file name without extension
+	Question string // markdown
+	Answer   string
+}
+
+// readDecks loads the cards under root by deck directory, e.g. "flags".
+func readDecks(root string) (map[string][]Card, error) {
+	decks := make(map[string][]Card)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(p) != ".md" || info.Name() == indexFile {
+			return nil
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		text := string(b)
+		var files []string
+		if i := strings.Index(text, provenanceTag); i > -1 {
+			files = provenanceFiles(text[i:])
+			text = text[:i]
+		}
+		if strings.HasPrefix(text, "---\n") {
+			if i := strings.Index(text[4:], "\n---\n"); i > -1 {
+				text = text[4+i+5:]
+			}
+		}
+		q, a, ok := splitCard(text)
+		if !ok {
+			return nil // not a card
+		}
+		deck, err := filepath.Rel(root, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		decks[deck] = append(decks[deck], Card{
+			Name:     strings.TrimSuffix(info.Name(), ".md"),
+			Question: resolveImages(strings.TrimSpace(q), filepath.Dir(p), files),
+			Answer:   resolveImages(strings.TrimSpace(a), filepath.Dir(p), files),
+		})
+		return nil
+	})
+	return decks, err
+}
+
+// provenanceFiles returns the commons file names recorded in the card's
+// provenance footer.
+func provenanceFiles(footer string) []string {
+	const prefix = "file: https://commons.wikimedia.org/wiki/File:"
+	var names []string
+	for _, line := range strings.Split(footer, "\n") {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(line, prefix)
+		if n, err := url.PathUnescape(name); err == nil {
+			name = n
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// commonsName returns the commons file stored as the local image, matched
+// against the card's source files as the stored name may differ: its
+// extension corrected from the content prefix b and unsafe characters
+// replaced. Unmatched images are assumed to keep their commons name.
+func commonsName(local string, b []byte, files []string) string {
+	for _, name := range files {
+		if safeFileName(fixExt(name, b)) == local || safeFileName(name) == local {
+			return name
+		}
+	}
+	return local
+}
+
+// resolveImages rewrites local image references of the card in dir as
+// commons urls, as exported cards are imported without the media
+// directories.
+func resolveImages(s, dir string, files []string) string {
+	return reMarkdownImage.ReplaceAllStringFunc(s, func(m string) string {
+		v := reMarkdownImage.FindStringSubmatch(m)
+		ref := v[2]
+		if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+			return m
+		}
+		if r, err := url.PathUnescape(ref); err == nil {
+			ref = r
+		}
+		b, _ := readPrefix(filepath.Join(dir, filepath.FromSlash(ref)))
+		name := commonsName(path.Base(ref), b, files)
+		return "![" + v[1] + "](" + wikiThumbURL(name, *flagImageWidth) + ")"
+	})
+}
+
+// readPrefix reads the start of the file for sniffing its content type.
+func readPrefix(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, b)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	return b[:n], err
+}
+
+// plainText flattens card markdown to a single line, images become their
+// url.
+func plainText(s string) string {
+	s = reMarkdownImage.ReplaceAllString(s, "$2")
+	s = strings.Replace(s, "**", "", -1)
+	s = reHTMLTag.ReplaceAllString(s, "")
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " / ")
+}
+
+// deckFileName names an export file by deck, e.g. "landlocked/regions" ->
+// "landlocked_regions".
+func deckFileName(deck string) string {
+	if deck == "." {
+		return "countries"
+	}
+	return strings.Replace(filepath.ToSlash(deck), "/", "_", -1)
+}
+
+// quizletMaxCards is the size limit of a Quizlet set.
+const quizletMaxCards = 2000
+
+// exportQuizlet writes a term/definition TSV per deck. Decks over the set
+// limit are split with --quizlet-split.
+func exportQuizlet(dir, deck string, cards []Card) error {
+	sets := [][]Card{cards}
+	if *flagQuizletSplit && len(cards) > quizletMaxCards {
+		sets = nil
+		for len(cards) > 0 {
+			n := quizletMaxCards
+			if len(cards) < n {
+				n = len(cards)
+			}
+			sets = append(sets, cards[:n])
+			cards = cards[n:]
+		}
+	}
+	for i, set := range sets {
+		var b strings.Builder
+		for _, c := range set {
+			fmt.Fprintf(&b, "%s\t%s\n", plainText(c.Question), plainText(c.Answer))
+		}
+		name := deckFileName(deck)
+		if len(sets) > 1 {
+			name = fmt.Sprintf("%s_%d", name, i+1)
+		}
+		if err := writeOutput(filepath.Join(dir, name+".tsv"), []byte(b.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportSuperMemo writes the deck in the SuperMemo Q&A text format, every
+// line prefixed by Q: or A: and cards separated by a blank line.
+func exportSuperMemo(dir, deck string, cards []Card) error {
+	var b strings.Builder
+	for i, c := range cards {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		for _, side := range []struct{ prefix, text string }{
+			{"Q: ", c.Question},
+			{"A: ", c.Answer},
+		} {
+			text := reMarkdownImage.ReplaceAllString(side.text, `<img src="$2" alt="$1">`)
+			text = reMarkdownBold.ReplaceAllString(text, "<b>$1</b>")
+			for _, line := range strings.Split(text, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					b.WriteString(side.prefix + line + "\n")
+				}
+			}
+		}
+	}
+	return writeOutput(filepath.Join(dir, deckFileName(deck)+".txt"), []byte(b.String()))
+}
+
+// exportOrgDrill writes the deck as Emacs org-drill entries, the answer in a
+// sub heading.
+func exportOrgDrill(dir, deck string, cards []Card) error {
+	org := func(s string) string {
+		s = reMarkdownImage.ReplaceAllString(s, "[[$2]]")
+		s = reMarkdownBold.ReplaceAllString(s, "*$1*")
+		return strings.TrimSpace(s)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "#+TITLE: %s\n\n", deckFileName(deck))
+	for _, c := range cards {
+		fmt.Fprintf(&b, "* %s :drill:\n%s\n** Answer\n%s\n\n", c.Name, org(c.Question), org(c.Answer))
+	}
+	return writeOutput(filepath.Join(dir, deckFileName(deck)+".org"), []byte(b.String()))
+}
+
+// exporters by format name.
+var exporters = map[string]func(dir, deck string, cards []Card) error{
+	"quizlet":   exportQuizlet,
+	"supermemo": exportSuperMemo,
+	"org-drill": exportOrgDrill,
+	"quiz":      exportQuiz,
+}
+
+// exportDecks exports the generated cards under root in the format to
+// exports/<format>.
+func exportDecks(format, root string) error {
+	export, ok := exporters[format]
+	if !ok {
+		return fmt.Errorf("unknown export format %q", format)
+	}
+	decks, err := readDecks(root)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(decks))
+	for deck := range decks {
+		names = append(names, deck)
+	}
+	sort.Strings(names)
+	dir := filepath.Join("exports", format)
+	for _, deck := range names {
+		cards := decks[deck]
+		sort.Slice(cards, func(i, j int) bool { return cards[i].Name < cards[j].Name })
+		if err := export(dir, deck, cards); err != nil {
+			return fmt.Errorf("%s: %w", deck, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommonsName(t *testing.T) {
+	png := []byte(pngHeader)
+	long := strings.Repeat("Very_long_map_caption_", 12) + "map.png"
+	tests := []struct {
+		files []string
+		b     []byte
+		want  string
+	}{
+		{[]string{"Flag_of_Nepal.svg"}, nil, "Flag_of_Nepal.svg"},
+		{[]string{"Map:Africa?.png"}, png, "Map:Africa?.png"},
+		{[]string{"Flag_of_Ghana.svg", "Ghana_map.PNG"}, png, "Ghana_map.PNG"},
+		{[]string{"Ghana_location"}, png, "Ghana_location"},
+		{[]string{"Ghana_map.svg"}, png, "Ghana_map.svg"},
+		{[]string{long}, png, long},
+	}
+	for _, tt := range tests {
+		name := tt.files[len(tt.files)-1]
+		local := safeFileName(fixExt(name, tt.b))
+		if got := commonsName(local, tt.b, tt.files); got != tt.want {
+			t.Errorf("commonsName(%q) = %q, want %q", local, got, tt.want)
+		}
+	}
+	if got := commonsName("Unknown.svg", nil, nil); got != "Unknown.svg" {
+		t.Errorf("unmatched image renamed to %q", got)
+	}
+}
+
+func TestResolveImages(t *testing.T) {
+	withFetcher(t, fetcher)
+	dir := filepath.Join("countries", "images")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	local := safeFileName(fixExt("Ghana_map.svg", []byte(pngHeader)))
+	if err := ioutil.WriteFile(filepath.Join(dir, local), []byte(pngHeader), 0666); err != nil {
+		t.Fatal(err)
+	}
+	footer := provenanceTag + "\nfile: " + filePageURL("Ghana_map.svg") + "\n-->"
+	got := resolveImages("![Map](images/"+escapeURLName(local)+")", "countries", provenanceFiles(footer))
+	want := "![Map](" + wikiThumbURL("Ghana_map.svg", *flagImageWidth) + ")"
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// getHighestPoint returns the name and elevation in metres of the highest
+// point of the country, empty if unknown.
+func getHighestPoint(uname string, refresh bool) (string, float64, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return "", 0, err
+	}
+	ids := e.ItemIDs("P610") // highest point
+	if len(ids) == 0 {
+		return "", 0, nil
+	}
+	point, err := getEntityByID(ids[0], refresh)
+	if err != nil {
+		return "", 0, err
+	}
+	var elevation float64
+	if vs := point.Quantities("P2044"); len(vs) > 0 { // elevation above sea level
+		elevation = vs[0]
+	}
+	return point.Label(), elevation, nil
+}
+
+const (
+	qLandlocked   = "Q123480" // landlocked country
+	qIslandNation = "Q112099" // island nation
+	qLake         = "Q23397"  // lake
+	qRiver        = "Q4022"   // river
+)
+
+func hasID(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// getLabels resolves the english labels of the entity IDs.
+func getLabels(ids []string, refresh bool) ([]string, error) {
+	labels := make([]string, 0, len(ids))
+	for _, id := range ids {
+		e, err := getEntityByID(id, refresh)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, e.Label())
+	}
+	return labels, nil
+}
+
+// getContinents returns the continents of the country.
+func getContinents(uname string, refresh bool) ([]string, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	return getLabels(e.ItemIDs("P30"), refresh) // continent
+}
+
+// getWaters returns the oceans and seas the country borders, lakes and
+// rivers are skipped.
+func getWaters(uname string, refresh bool) ([]string, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	var waters []string
+	for _, id := range e.ItemIDs("P206") { // located in or next to body of water
+		w, err := getEntityByID(id, refresh)
+		if err != nil {
+			return nil, err
+		}
+		kinds := w.ItemIDs("P31") // instance of
+		if hasID(kinds, qLake) || hasID(kinds, qRiver) {
+			continue
+		}
+		waters = append(waters, w.Label())
+	}
+	sort.Strings(waters)
+	return waters, nil
+}
+
+// classifyGeography sets the landlocked and island status of the country
+// from its wikidata classification.
+func classifyGeography(c *Country, uname string, refresh bool) error {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return err
+	}
+	kinds := e.ItemIDs("P31") // instance of
+	c.Landlocked = hasID(kinds, qLandlocked)
+	c.Island = hasID(kinds, qIslandNation)
+
+	if c.Landlocked {
+		c.Tags = append(c.Tags, "landlocked")
+	}
+	if c.Island {
+		c.Tags = append(c.Tags, "island")
+	}
+	return nil
+}
+
+// CountryList is an aggregate card listing countries.
+type CountryList struct {
+	Question  string
+	Countries []string
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("country-list").Parse(`{{.Question}}
+<!--question-->
+{{range .Countries}}- {{.}}
+{{end}}`))
+}
+
+// makeRegionLists renders the landlocked and island countries per continent.
+func makeRegionLists(countries []Country) error {
+	landlocked := make(map[string][]string)
+	islands := make(map[string][]string)
+	for _, c := range countries {
+		for _, continent := range c.Continents {
+			if c.Landlocked {
+				landlocked[continent] = append(landlocked[continent], c.Name)
+			}
+			if c.Island {
+				islands[continent] = append(islands[continent], c.Name)
+			}
+		}
+	}
+
+	dir := filepath.Join("countries", "landlocked", "regions")
+	for _, g := range []struct {
+		prefix, format string
+		lists          map[string][]string
+	}{
+		{"landlocked_", "Name the landlocked countries of **%s**.", landlocked},
+		{"island_", "Name the island nations of **%s**.", islands},
+	} {
+		for _, continent := range sortedKeys(g.lists) {
+			names := g.lists[continent]
+			sort.Strings(names)
+			l := CountryList{
+				Question:  fmt.Sprintf(g.format, continent),
+				Countries: names,
+			}
+			if err := makeTmpl(dir, g.prefix+toURLName(continent), "country-list", &l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getCurrencies returns the names of the currencies used by the country.
+func getCurrencies(uname string, refresh bool) ([]string, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	return getLabels(e.ItemIDs("P38"), refresh) // currency
+}
+
+// makeCurrencyLists renders a card per currency listing the countries that
+// use it.
+func makeCurrencyLists(countries []Country) error {
+	users := make(map[string][]string)
+	for _, c := range countries {
+		for _, currency := range c.Currencies {
+			users[currency] = append(users[currency], c.Name)
+		}
+	}
+
+	dir := filepath.Join("countries", "currencies")
+	for _, currency := range sortedKeys(users) {
+		names := users[currency]
+		sort.Strings(names)
+		l := CountryList{
+			Question:  fmt.Sprintf("Which countries use the **%s**?", currency),
+			Countries: names,
+		}
+		if err := makeTmpl(dir, toURLName(currency), "country-list", &l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDrivesOn normalises the infobox drives_on value to left or right.
+func parseDrivesOn(s string) string {
+	s = strings.ToLower(cleanWikiText(s))
+	switch {
+	case strings.HasPrefix(s, "left"):
+		return "left"
+	case strings.HasPrefix(s, "right"):
+		return "right"
+	}
+	return ""
+}
+
+// CountryGroups is an aggregate card listing countries grouped by region.
+type CountryGroups struct {
+	Question string
+	Groups   []CountryGroup
+}
+
+type CountryGroup struct {
+	Name      string // empty if ungrouped
+	Countries []string
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("country-groups").Parse(`{{.Question}}
+<!--question-->
+{{range .Groups}}{{if .Name}}
+**{{.Name}}**
+
+{{end}}{{range .Countries}}- {{.}}
+{{end}}{{end}}`))
+}
+
+// groupByContinent groups the countries by their first continent, if known.
+func groupByContinent(countries []*Country) []CountryGroup {
+	index := make(map[string]int)
+	var groups []CountryGroup
+	for _, c := range countries {
+		var continent string
+		if len(c.Continents) > 0 {
+			continent = c.Continents[0]
+		}
+		i, ok := index[continent]
+		if !ok {
+			i = len(groups)
+			index[continent] = i
+			groups = append(groups, CountryGroup{Name: continent})
+		}
+		groups[i].Countries = append(groups[i].Countries, c.Name)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	for _, g := range groups {
+		sort.Strings(g.Countries)
+	}
+	return groups
+}
+
+// makeLeftDriving renders the summary card of left driving countries.
+func makeLeftDriving(countries []Country) error {
+	var left []*Country
+	for i := range countries {
+		if countries[i].DrivesOn == "left" {
+			left = append(left, &countries[i])
+		}
+	}
+	g := CountryGroups{
+		Question: "Which countries drive on the **left**?",
+		Groups:   groupByContinent(left),
+	}
+	return makeTmpl(filepath.Join("countries", "driving"), "left", "country-groups", &g)
+}
+
+var (
+	// {{start date|df=yes|1960|8|1}}, {{Start date and age|1960|08|01}}
+	reDateTemplate = regexp.MustCompile(`(?i){{\s*(?:start date|start date and age|date)\s*\|([^}]*)}}`)
+
+	reYear = regexp.MustCompile(`\b(1[0-9]{3}|20[0-9]{2})\b`)
+)
+
+// dateLayouts are the textual dates of infoboxes, formatted as the first.
+var dateLayouts = []string{"2 January 2006", "January 2, 2006", "2006-01-02"}
+
+// dayLayouts are the days in the year of wikidata labels, formatted as the
+// first.
+var dayLayouts = []string{"2 January", "January 2"}
+
+// parseWikiDate parses the first date in the wikitext, returning the date as
+// "1 August 1960" in full or "1960" with yearOnly. Date templates are parsed
+// before the text is cleaned as cleaning drops them. Partial dates fall back
+// to the year.
+func parseWikiDate(s string, yearOnly bool) string {
+	if v := reDateTemplate.FindStringSubmatch(s); len(v) == 2 {
+		var nums []int
+		var text string
+		for _, p := range strings.Split(v[1], "|") {
+			p = strings.TrimSpace(p)
+			if strings.Contains(p, "=") { // df=yes
+				continue
+			}
+			if n, err := strconv.Atoi(p); err == nil {
+				nums = append(nums, n)
+			} else if text == "" {
+				text = p
+			}
+		}
+		if len(nums) >= 3 && !yearOnly {
+			t := time.Date(nums[0], time.Month(nums[1]), nums[2], 0, 0, 0, 0, time.UTC)
+			return t.Format(dateLayouts[0])
+		}
+		if len(nums) >= 1 {
+			return strconv.Itoa(nums[0])
+		}
+		if text != "" {
+			// {{date|1 August 1960}}
+			s = text
+		}
+	}
+	s = cleanWikiText(s)
+	if !yearOnly {
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format(dateLayouts[0])
+			}
+		}
+	}
+	return reYear.FindString(s)
+}
+
+// parseDay formats a day in the year as "14 July" whatever the source
+// order, unparsed days are returned as is. Days have no year so are the same
+// in either date format.
+func parseDay(s string) string {
+	s = strings.TrimSpace(s)
+	for _, layout := range dayLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(dayLayouts[0])
+		}
+	}
+	return s
+}
+
+// parseIndependence finds the independence event in the infobox established
+// events.
+func parseIndependence(text string, yearOnly bool) string {
+	for i := 1; i < 20; i++ {
+		event := infoboxField(text, "established_event"+strconv.Itoa(i))
+		if event == "" {
+			if i > 1 {
+				break
+			}
+			continue
+		}
+		if strings.Contains(strings.ToLower(event), "independence") {
+			return parseWikiDate(infoboxField(text, "established_date"+strconv.Itoa(i)), yearOnly)
+		}
+	}
+	return ""
+}
+
+// getNationalDay returns the national or independence day of the country
+// from its public holidays, e.g. "14 July".
+func getNationalDay(uname string, refresh bool) (string, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return "", err
+	}
+	for _, id := range e.ItemIDs("P832") { // public holiday
+		holiday, err := getEntityByID(id, refresh)
+		if err != nil {
+			return "", err
+		}
+		label := strings.ToLower(holiday.Label())
+		if !strings.Contains(label, "national day") && !strings.Contains(label, "independence day") {
+			continue
+		}
+		days, err := getLabels(holiday.ItemIDs("P837"), refresh) // day in year for periodic occurrence
+		if err != nil {
+			return "", err
+		}
+		if len(days) > 0 {
+			return parseDay(days[0]), nil
+		}
+	}
+	return "", nil
+}
+
+// parseMotto returns the national motto and its english translation, if
+// given on the following line.
+func parseMotto(text string) (motto, translation string) {
+	lines := infoboxLines(text, "national_motto")
+	if len(lines) == 0 {
+		return "", ""
+	}
+	motto = strings.Trim(lines[0], `"“” `)
+	if len(lines) > 1 {
+		translation = strings.Trim(lines[1], `"“”() `)
+	}
+	if translation == motto {
+		translation = ""
+	}
+	return motto, translation
+}
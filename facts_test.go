@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/dustin/go-wikiparse"
+)
+
+func TestParseWikiDate(t *testing.T) {
+	tests := []struct {
+		in       string
+		full     string
+		yearOnly string
+	}{
+		{"{{start date|df=yes|1960|8|1}}", "1 August 1960", "1960"},
+		{"{{Start date and age|1960|08|01}}", "1 August 1960", "1960"},
+		{"{{date|1 August 1960}}", "1 August 1960", "1960"},
+		{"{{Date|August 1, 1960}}", "1 August 1960", "1960"},
+		{"{{date|1960-08-01}}", "1 August 1960", "1960"},
+		{"{{start date|1960}}", "1960", "1960"},
+		{"1 August 1960<ref>a</ref>", "1 August 1960", "1960"},
+		{"[[August 1]], [[1960]]", "1 August 1960", "1960"},
+		{"c. 1960", "1960", "1960"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := parseWikiDate(tt.in, false); got != tt.full {
+			t.Errorf("parseWikiDate(%q) = %q, want %q", tt.in, got, tt.full)
+		}
+		if got := parseWikiDate(tt.in, true); got != tt.yearOnly {
+			t.Errorf("parseWikiDate(%q, yearOnly) = %q, want %q", tt.in, got, tt.yearOnly)
+		}
+	}
+}
+
+func TestParseDay(t *testing.T) {
+	tests := map[string]string{
+		"July 14":    "14 July",
+		"14 July":    "14 July",
+		" March 6 ":  "6 March",
+		"Easter Day": "Easter Day",
+	}
+	for in, want := range tests {
+		if got := parseDay(in); got != want {
+			t.Errorf("parseDay(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDrivingContinents(t *testing.T) {
+	f := &memFetcher{}
+	f.addEntity("Q117", "Ghana", map[string][]string{"P30": {"Q15"}}, "Ghana")
+	f.addEntity("Q15", "Africa", nil)
+	withFetcher(t, f)
+
+	c := &Country{Name: "Ghana", UName: "Ghana"}
+	src := &Source{Page: &wikiparse.Page{Revisions: []wikiparse.Revision{{
+		Text: "{{Infobox country\n| drives_on = right\n}}",
+	}}}}
+	for _, ct := range cardTypes {
+		if ct.Name() == "driving" {
+			if err := ct.Extract(c, src); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if c.DrivesOn != "right" {
+		t.Errorf("drives on %q, want right", c.DrivesOn)
+	}
+	if len(c.Continents) != 1 || c.Continents[0] != "Africa" {
+		t.Errorf("continents %q, want [Africa]", c.Continents)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// failureSite is a file to fix after a run, printed as file:line.
+type failureSite struct {
+	Path    string
+	Line    int // 1 based, 0 to search for Marker
+	Marker  string
+	Message string
+}
+
+var (
+	failureSites   []failureSite
+	failureSitesMu sync.Mutex
+)
+
+// addFailureSite records a file needing a manual fix for --open-failures.
+func addFailureSite(s failureSite) {
+	failureSitesMu.Lock()
+	failureSites = append(failureSites, s)
+	failureSitesMu.Unlock()
+}
+
+// findLine returns the 1 based line of the file containing s, 1 if not
+// found.
+func findLine(path, s string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 1
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		if strings.Contains(sc.Text(), s) {
+			return n
+		}
+	}
+	return 1
+}
+
+// collectFailures returns the files to fix: skeleton answers, outputs that
+// differ in check mode and the countries.txt entries of failed and
+// implausible countries.
+func collectFailures() []failureSite {
+	failureSitesMu.Lock()
+	sites := append([]failureSite(nil), failureSites...)
+	failureSitesMu.Unlock()
+
+	checkDiffsMu.Lock()
+	for _, path := range checkDiffs {
+		sites = append(sites, failureSite{Path: path, Line: 1, Message: "differs from the generated output"})
+	}
+	checkDiffsMu.Unlock()
+
+	report.mu.Lock()
+	for name, err := range report.Failures {
+		sites = append(sites, failureSite{Path: "countries.txt", Marker: name, Message: name + ": " + err})
+	}
+	for _, a := range report.Anomalies {
+		uname := strings.SplitN(a, ": ", 2)[0]
+		sites = append(sites, failureSite{Path: "countries.txt", Marker: strings.Replace(uname, "_", " ", -1), Message: a})
+	}
+	report.mu.Unlock()
+
+	for i, s := range sites {
+		if s.Line == 0 {
+			sites[i].Line = findLine(s.Path, s.Marker)
+		}
+	}
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].Path != sites[j].Path {
+			return sites[i].Path < sites[j].Path
+		}
+		return sites[i].Line < sites[j].Line
+	})
+	return sites
+}
+
+// openFailures prints the files to fix as file:line: message, understood by
+// editors and terminals, then opens them in $EDITOR if set.
+func openFailures(w io.Writer) error {
+	sites := collectFailures()
+	if len(sites) == 0 {
+		return nil
+	}
+	var paths []string
+	seen := make(map[string]bool)
+	for _, s := range sites {
+		fmt.Fprintf(w, "%s:%d: %s\n", s.Path, s.Line, s.Message)
+		if !seen[s.Path] {
+			seen[s.Path] = true
+			paths = append(paths, s.Path)
+		}
+	}
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		return nil
+	}
+	cmd := exec.Command("sh", append([]string{"-c", editor + ` "$@"`, "editor"}, paths...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Fetcher retrieves wiki pages, commons files and API responses. Pages and
+// files are streamed as exports and media can be large.
+type Fetcher interface {
+	// Page returns the Special:Export xml of the page on the wiki host.
+	Page(host, uname string, refresh bool) (io.ReadCloser, error)
+	// File returns the contents of the commons file.
+	File(name string, refresh bool) (io.ReadCloser, error)
+	// Query returns the response of an API request, key identifies the
+	// request for caching.
+	Query(key, url string, refresh bool) ([]byte, error)
+}
+
+// rangeFetcher is implemented by fetchers able to resume file downloads.
+// FileRange returns the file from the byte offset if it is unchanged since
+// the etag validator was returned, partial reports whether the offset was
+// honored or the whole file is returned. The returned etag identifies the
+// file for the next resume, empty if it can't be resumed. An offset at the
+// end of the file returns errRangeNotSatisfiable.
+type rangeFetcher interface {
+	FileRange(name string, offset int64, etag string) (r io.ReadCloser, next string, partial bool, err error)
+}
+
+// fetcher is used by all page, file and API requests.
+var fetcher Fetcher = &cacheFetcher{next: httpFetcher{}}
+
+// client is shared by all requests so connections are reused.
+var client = http.DefaultClient
+
+// setupClient configures the shared client from the proxy and timeout flags.
+// Without a proxy flag HTTPS_PROXY and friends are honored.
+func setupClient() error {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	if *flagProxy != "" {
+		u, err := url.Parse(*flagProxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %w", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	t.MaxIdleConnsPerHost = 4
+	// The timeout bounds connecting and waiting for the response, not
+	// reading the body, so large media downloads aren't cut off.
+	t.DialContext = (&net.Dialer{
+		Timeout:   *flagTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	t.TLSHandshakeTimeout = *flagTimeout
+	t.ResponseHeaderTimeout = *flagTimeout
+	client = &http.Client{Transport: t}
+	return nil
+}
+
+// httpFetcher fetches from wikipedia and commons, refresh is ignored.
+type httpFetcher struct{}
+
+func (httpFetcher) Page(host, uname string, refresh bool) (io.ReadCloser, error) {
+	return open("https://" + host + "/wiki/Special:Export/" + escapeURLName(uname))
+}
+
+func (httpFetcher) File(name string, refresh bool) (io.ReadCloser, error) {
+	rsp, err := openRange(wikiFileURL(name), 0, "")
+	if err != nil {
+		return nil, err
+	}
+	return rejectHTML(name, rsp.Header.Get("Content-Type"), rsp.Body)
+}
+
+// FileRange resumes the file from the byte offset, see rangeFetcher.
+func (httpFetcher) FileRange(name string, offset int64, etag string) (io.ReadCloser, string, bool, error) {
+	rsp, err := openRange(wikiFileURL(name), offset, etag)
+	if err != nil {
+		return nil, "", false, err
+	}
+	next := validator(rsp.Header)
+	if rsp.StatusCode == http.StatusPartialContent {
+		return rsp.Body, next, true, nil
+	}
+	r, err := rejectHTML(name, rsp.Header.Get("Content-Type"), rsp.Body)
+	return r, next, false, err
+}
+
+func (httpFetcher) Query(key, url string, refresh bool) ([]byte, error) {
+	return get(url)
+}
+
+// cacheFetcher caches responses of the next fetcher on disk, gzipped pages
+// under pages/, files under files/ and queries at their key path. Refresh
+// bypasses the cache.
+type cacheFetcher struct {
+	next  Fetcher
+	parts sync.Map // path to *sync.Mutex guarding its .part file
+}
+
+// cached opens the cache file at path, filling it from fetch on a miss.
+// Responses are streamed to disk so are never held in memory.
+func (f *cacheFetcher) cached(path string, compress, refresh bool, fetch func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if !refresh {
+		if r, err := openCache(path); err == nil {
+			report.cacheHit()
+			return r, nil
+		}
+	}
+	r, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if err := writeCache(path, r, compress); err != nil {
+		return nil, err
+	}
+	return openCache(path)
+}
+
+func (f *cacheFetcher) Page(host, uname string, refresh bool) (io.ReadCloser, error) {
+	return f.cached(pagePath(host, uname), true, refresh, func() (io.ReadCloser, error) {
+		return f.next.Page(host, uname, refresh)
+	})
+}
+
+// File downloads to a .part file kept on failure, so large files are
+// resumed with a range request rather than downloaded again. The file's
+// validator is stored next to the .part file, the range is only honored if
+// the file is unchanged, a .part file without one is downloaded again.
+func (f *cacheFetcher) File(name string, refresh bool) (io.ReadCloser, error) {
+	path := filepath.Join("files", name)
+	if !refresh {
+		if r, err := os.Open(path); err == nil {
+			report.cacheHit()
+			return r, nil
+		}
+	}
+	part := path + ".part"
+	etagPath := part + ".etag"
+	mu, _ := f.parts.LoadOrStore(path, new(sync.Mutex))
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+	if !refresh {
+		// Downloaded while waiting.
+		if r, err := os.Open(path); err == nil {
+			report.cacheHit()
+			return r, nil
+		}
+	}
+
+	var offset int64
+	var etag string
+	if b, err := ioutil.ReadFile(etagPath); err == nil && !refresh {
+		if info, err := os.Stat(part); err == nil {
+			offset, etag = info.Size(), string(b)
+		}
+	}
+
+	var r io.ReadCloser
+	var next string
+	var err error
+	if rf, ok := f.next.(rangeFetcher); ok {
+		var partial bool
+		r, next, partial, err = rf.FileRange(name, offset, etag)
+		if errors.Is(err, errRangeNotSatisfiable) {
+			// The validator matched so the .part file is complete.
+			return f.finishPart(part, path)
+		}
+		if err == nil && !partial {
+			offset = 0
+		}
+	} else {
+		offset = 0
+		r, err = f.next.File(name, refresh)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if offset > 0 {
+		flags = os.O_WRONLY | os.O_APPEND
+	}
+	w, err := os.OpenFile(part, flags, 0666)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 {
+		// A new download, the stored validator must match its bytes.
+		if err := writeETag(etagPath, next); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("%s: %w, resume by rerunning", name, err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return f.finishPart(part, path)
+}
+
+// finishPart moves the downloaded .part file into the cache.
+func (f *cacheFetcher) finishPart(part, path string) (io.ReadCloser, error) {
+	if err := os.Rename(part, path); err != nil {
+		return nil, err
+	}
+	os.Remove(part + ".etag")
+	return os.Open(path)
+}
+
+// writeETag stores the validator of a new .part file, removing a stale one
+// if the file can't be resumed.
+func writeETag(path, etag string) error {
+	if etag == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return ioutil.WriteFile(path, []byte(etag), 0666)
+}
+
+func (f *cacheFetcher) Query(key, url string, refresh bool) ([]byte, error) {
+	r, err := f.cached(key, false, refresh, func() (io.ReadCloser, error) {
+		body, err := f.next.Query(key, url, refresh)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// memFetcher is an in memory Fetcher for running extraction without the
+// network. Pages are keyed by host/uname, files by name and queries by key.
+type memFetcher struct {
+	mu      sync.Mutex
+	Pages   map[string][]byte
+	Files   map[string][]byte
+	Queries map[string][]byte
+}
+
+func (f *memFetcher) lookup(m map[string][]byte, key string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("404 Not Found %s", key)
+	}
+	return body, nil
+}
+
+func (f *memFetcher) open(m map[string][]byte, key string) (io.ReadCloser, error) {
+	body, err := f.lookup(m, key)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(body)), nil
+}
+
+func (f *memFetcher) Page(host, uname string, refresh bool) (io.ReadCloser, error) {
+	return f.open(f.Pages, host+"/"+uname)
+}
+
+func (f *memFetcher) File(name string, refresh bool) (io.ReadCloser, error) {
+	return f.open(f.Files, name)
+}
+
+func (f *memFetcher) Query(key, url string, refresh bool) ([]byte, error) {
+	return f.lookup(f.Queries, key)
+}
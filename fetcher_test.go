@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exportXML wraps the wikitext as a Special:Export page.
+func exportXML(title, text string) []byte {
+	var b strings.Builder
+	b.WriteString("<mediawiki><siteinfo><sitename>Wikipedia</sitename></siteinfo><page><title>")
+	xml.EscapeText(&b, []byte(title))
+	b.WriteString("</title><ns>0</ns><id>1</id><revision><id>42</id><text>")
+	xml.EscapeText(&b, []byte(text))
+	b.WriteString("</text></revision></page></mediawiki>")
+	return []byte(b.String())
+}
+
+// existsKey is the cache key of the fileExists query.
+func existsKey(name string) string {
+	return filepath.Join("pages", commons, "exists", name+".json")
+}
+
+// addEntity serves the wikidata item with its item valued claims, under
+// its ID and the titles linked to it. Labels after the first are aliases.
+func (f *memFetcher) addEntity(id, label string, claims map[string][]string, titles ...string) {
+	labels := strings.Split(label, "|")
+	e := Entity{
+		ID:      id,
+		Labels:  map[string]wdText{"en": {Language: "en", Value: labels[0]}},
+		Aliases: make(map[string][]wdText),
+		Claims:  make(map[string][]wdStatement),
+	}
+	for _, a := range labels[1:] {
+		e.Aliases["en"] = append(e.Aliases["en"], wdText{Language: "en", Value: a})
+	}
+	for prop, ids := range claims {
+		for _, v := range ids {
+			var st wdStatement
+			st.Rank = "normal"
+			st.Mainsnak.Datavalue.Type = "wikibase-entityid"
+			st.Mainsnak.Datavalue.Value = json.RawMessage(`{"id":"` + v + `"}`)
+			e.Claims[prop] = append(e.Claims[prop], st)
+		}
+	}
+	b, err := json.Marshal(map[string]map[string]Entity{"entities": {id: e}})
+	if err != nil {
+		panic(err)
+	}
+	if f.Queries == nil {
+		f.Queries = make(map[string][]byte)
+	}
+	for _, name := range append(titles, id) {
+		f.Queries[filepath.Join("pages", wikidata, name+".json")] = b
+	}
+}
+
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+// withFetcher runs the test in a temporary directory with the fetcher.
+func withFetcher(t *testing.T, f Fetcher) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "deck-countries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	old := fetcher
+	fetcher = f
+	t.Cleanup(func() {
+		fetcher = old
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	})
+}
+
+func TestMemFetcherCountry(t *testing.T) {
+	const text = `{{Infobox country
+| conventional_long_name = Republic of Testland
+| image_flag = Flag of Testland.svg
+| image_map = Testland (orthographic projection).svg
+| capital = [[Testville]]
+}}
+'''Testland''' is a country.`
+	f := &memFetcher{
+		Pages: map[string][]byte{
+			wikipedia + "/Testland": exportXML("Testland", text),
+		},
+		Files: map[string][]byte{
+			"Flag_of_Testland.svg":                   []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`),
+			"Testland_(orthographic_projection).svg": []byte(pngHeader),
+		},
+		Queries: make(map[string][]byte),
+	}
+	for name := range f.Files {
+		f.Queries[existsKey(name)] = []byte(`{"query":{"pages":[{"title":"File:` + name + `"}]}}`)
+	}
+	withFetcher(t, f)
+
+	// Location answers are hand written.
+	if err := writeOutput(filepath.Join("countries", "Testland_location.md"),
+		[]byte("Where in the world is **Testland**?\n<!--question-->\nIn the sea.")); err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := getWikiPage(wikipedia, "Testland", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &Source{Page: page}
+	c, err := parseCountry("Testland", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Capital != "Testville" {
+		t.Errorf("capital %q, want Testville", c.Capital)
+	}
+	if c.FlagName != "Flag_of_Testland.svg" {
+		t.Errorf("flag %q", c.FlagName)
+	}
+	// The map is a png whatever its name says.
+	if c.MapImageURL != "images/Testland_(orthographic_projection).svg.png" {
+		t.Errorf("map url %q", c.MapImageURL)
+	}
+	if err := renderCountry(c, src); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join("countries", "capitals", "Testland.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "What is the capital of **Testland**?\n<!--question-->\nTestville") {
+		t.Errorf("capital card:\n%s", b)
+	}
+	b, err = ioutil.ReadFile(filepath.Join("countries", "Testland_location.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "In the sea.") {
+		t.Errorf("location card lost its answer:\n%s", b)
+	}
+	for _, path := range []string{
+		filepath.Join("countries", "Testland.md"),
+		filepath.Join("countries", "images", "Testland_(orthographic_projection).svg.png"),
+		filepath.Join("countries", "flags", "images", "Flag_of_Testland.svg"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// rangeFake serves a file honoring ranges while its etag matches.
+type rangeFake struct {
+	memFetcher
+	body []byte
+	etag string
+}
+
+func (f *rangeFake) FileRange(name string, offset int64, etag string) (io.ReadCloser, string, bool, error) {
+	if offset > 0 && etag == f.etag {
+		if offset >= int64(len(f.body)) {
+			return nil, "", false, errRangeNotSatisfiable
+		}
+		return ioutil.NopCloser(bytes.NewReader(f.body[offset:])), f.etag, true, nil
+	}
+	return ioutil.NopCloser(bytes.NewReader(f.body)), f.etag, false, nil
+}
+
+func TestCacheFetcherResume(t *testing.T) {
+	tests := []struct {
+		name string
+		part string // .part contents
+		etag string // stored validator, empty for none
+	}{
+		{"resume", "hello", `"v2"`},
+		{"changed", "HELLO", `"v1"`},
+		{"complete", "hello world", `"v2"`},
+		{"no validator", "HELLO", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withFetcher(t, fetcher)
+			path := filepath.Join("files", "Map.svg")
+			if err := os.MkdirAll("files", 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path+".part", []byte(tt.part), 0666); err != nil {
+				t.Fatal(err)
+			}
+			if tt.etag != "" {
+				if err := ioutil.WriteFile(path+".part.etag", []byte(tt.etag), 0666); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			f := &cacheFetcher{next: &rangeFake{body: []byte("hello world"), etag: `"v2"`}}
+			r, err := f.File("Map.svg", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "hello world" {
+				t.Errorf("got %q, want %q", b, "hello world")
+			}
+			for _, p := range []string{path + ".part", path + ".part.etag"} {
+				if _, err := os.Stat(p); !os.IsNotExist(err) {
+					t.Errorf("%s left behind", p)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const flagFeaturesFile = "flag_features.txt"
+
+// FlagFeature is a bundled group of flags sharing a feature, e.g. a star.
+type FlagFeature struct {
+	Name      string // card file name, e.g. star
+	Question  string
+	Countries []string
+}
+
+// readFlagFeatures parses the bundled dataset, one feature per line as
+// "feature | question | countries", skipping blank lines and # comments.
+func readFlagFeatures(path string) ([]FlagFeature, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var features []FlagFeature
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: want 3 fields, got %d", path, i+1, len(fields))
+		}
+		features = append(features, FlagFeature{
+			Name:      strings.TrimSpace(fields[0]),
+			Question:  strings.TrimSpace(fields[1]),
+			Countries: splitList(fields[2]),
+		})
+	}
+	return features, nil
+}
+
+// makeFlagFeatures renders a card per flag feature listing the generated
+// countries with it.
+func makeFlagFeatures(countries []Country) error {
+	features, err := readFlagFeatures(flagFeaturesFile)
+	if err != nil {
+		return err
+	}
+	generated := make(map[string]bool, len(countries))
+	for _, c := range countries {
+		generated[c.UName] = true
+	}
+
+	dir := filepath.Join("countries", "flags", "features")
+	for _, f := range features {
+		var names []string
+		for _, name := range f.Countries {
+			if generated[toURLName(name)] {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		sort.Strings(names)
+		l := CountryList{Question: f.Question, Countries: names}
+		if err := makeTmpl(dir, f.Name, "country-list", &l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
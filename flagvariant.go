@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Flag variant policies, which flag is preferred when a country has several.
+const (
+	flagVariantCivil = "civil" // the canonical Flag_of_X.svg
+	flagVariantState = "state" // the state flag, else the canonical flag
+)
+
+func validFlagVariant(policy string) error {
+	switch policy {
+	case flagVariantCivil, flagVariantState:
+		return nil
+	}
+	return fmt.Errorf("invalid flag variant %q, want %s or %s", policy, flagVariantCivil, flagVariantState)
+}
+
+var (
+	// Flag_of_Honduras_(darker_variant).svg, Civil_ensign_of_X.svg
+	reFlagVariant = regexp.MustCompile(`(?i)variant|state_flag|civil_(ensign|flag)|_\((state|civil)\)`)
+
+	// State_flag_of_X.svg, Flag_of_X_(state).svg
+	reStateFlag = regexp.MustCompile(`(?i)state_flag|_\(state\)`)
+)
+
+// canonicalFlagNames are the canonical commons flags of the country.
+func canonicalFlagNames(uname string) []string {
+	return []string{
+		"Flag_of_" + uname + ".svg",
+		"Flag_of_the_" + uname + ".svg",
+	}
+}
+
+// stateFlagNames are the conventional commons state flags of the country.
+func stateFlagNames(uname string) []string {
+	return []string{
+		"State_flag_of_" + uname + ".svg",
+		"Flag_of_" + uname + "_(state).svg",
+	}
+}
+
+// firstExisting returns the first existing commons file, empty if none.
+func firstExisting(names []string, refresh bool) (string, error) {
+	for _, name := range names {
+		ok, err := fileExists(name, refresh)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// extractFlagName parses the infobox flag file, replacing variants and
+// missing files by the flag the --flag-variant policy prefers.
+func extractFlagName(text, uname string, refresh bool) (string, []string, error) {
+	var name string
+	var msgs []string
+	if v := reImageFlag.FindStringSubmatch(text); len(v) == 2 {
+		name, msgs = parseWikiFile(v[1])
+	}
+	if *flagFlagVariant == flagVariantState {
+		if name != "" && reStateFlag.MatchString(name) {
+			ok, err := fileExists(name, refresh)
+			if err != nil {
+				return "", nil, err
+			}
+			if ok {
+				return name, msgs, nil
+			}
+		}
+		x, err := firstExisting(stateFlagNames(uname), refresh)
+		if err != nil {
+			return "", nil, err
+		}
+		if x != "" {
+			return x, nil, nil
+		}
+	}
+	if name != "" && !reFlagVariant.MatchString(name) {
+		ok, err := fileExists(name, refresh)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok {
+			return name, msgs, nil
+		}
+	}
+	x, err := firstExisting(canonicalFlagNames(uname), refresh)
+	if err != nil {
+		return "", nil, err
+	}
+	if x != "" {
+		if name != "" && !strings.EqualFold(x, name) {
+			msgs = append(msgs, fmt.Sprintf("infobox flag %s replaced by %s", name, x))
+		}
+		return x, msgs, nil
+	}
+	if name == "" {
+		return "", nil, fmt.Errorf("image flag failed: no infobox or canonical flag")
+	}
+	return name, msgs, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"strconv"
+)
+
+// genDataPackage writes the extracted countries as the countriesdata Go
+// package in dir, so applications can embed the dataset without scraping.
+func genDataPackage(dir string, countries []Country) error {
+	var b bytes.Buffer
+	b.WriteString(`// Code generated by deck-countries; DO NOT EDIT.
+
+// Package countriesdata is the dataset of UN member states extracted from
+// Wikipedia and Wikidata.
+package countriesdata
+
+// Country is an extracted UN member state. Image files are Wikimedia
+// Commons file names.
+type Country struct {
+	Name       string
+	URLName    string // wikipedia article name
+	Capital    string
+	FlagFile   string
+	MapFile    string
+	Continents []string
+	Population float64 // zero if not extracted
+	Area       float64 // km², zero if not extracted
+}
+
+// Countries are sorted by name.
+var Countries = []Country{
+`)
+	for _, c := range countries {
+		fmt.Fprintf(&b, "{\nName: %q,\nURLName: %q,\nCapital: %q,\nFlagFile: %q,\nMapFile: %q,\n",
+			c.Name, c.UName, c.Capital, c.FlagName, c.MapName)
+		if len(c.Continents) > 0 {
+			fmt.Fprintf(&b, "Continents: %#v,\n", c.Continents)
+		}
+		if c.Population > 0 {
+			fmt.Fprintf(&b, "Population: %s,\n", strconv.FormatFloat(c.Population, 'f', -1, 64))
+		}
+		if c.Area > 0 {
+			fmt.Fprintf(&b, "Area: %s,\n", strconv.FormatFloat(c.Area, 'f', -1, 64))
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("countriesdata: %w", err)
+	}
+	return writeOutput(filepath.Join(dir, "countriesdata.go"), src)
+}
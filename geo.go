@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// earthRadius is the mean radius of the earth in km.
+const earthRadius = 6371.0
+
+// LatLon is a point on the earth in degrees.
+type LatLon struct {
+	Lat, Lon float64
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+// distance returns the great circle distance between the points in km,
+// using the haversine formula.
+func distance(a, b LatLon) float64 {
+	dLat := radians(b.Lat - a.Lat)
+	dLon := radians(b.Lon - a.Lon)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(a.Lat))*math.Cos(radians(b.Lat))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Coordinates returns the points of a globe coordinate property.
+func (e *Entity) Coordinates(prop string) []LatLon {
+	var vs []LatLon
+	for _, s := range e.statements(prop) {
+		var v struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		}
+		if err := json.Unmarshal(s.Mainsnak.Datavalue.Value, &v); err != nil {
+			continue
+		}
+		vs = append(vs, LatLon{Lat: v.Latitude, Lon: v.Longitude})
+	}
+	return vs
+}
@@ -4,5 +4,6 @@ go 1.15
 
 require (
 	github.com/dustin/go-wikiparse v0.0.0-20180421171717-b202c3048fd5
-	golang.org/x/time v0.0.0-20201208040808-7e3f01d25324 // indirect
+	golang.org/x/text v0.13.0
+	golang.org/x/time v0.0.0-20201208040808-7e3f01d25324
 )
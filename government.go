@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// reMonarchy matches forms of government with a hereditary head of state.
+var reMonarchy = regexp.MustCompile(`(?i)monarchy|emirate|sultanate|kingdom|principality|grand duchy`)
+
+func init() {
+	tmpls = template.Must(tmpls.New("government").Parse(`{{template "front-matter" front nil .Tags}}What is the form of government of **{{.Name}}**?
+<!--question-->
+**{{.Government}}**`))
+}
+
+// extractGovernment parses the infobox government type, tagging
+// monarchies.
+func extractGovernment(c *Country, src *Source) error {
+	lines := infoboxLines(src.Text(), "government_type")
+	if len(lines) == 0 {
+		report.fallback(c.UName, "government")
+		return nil
+	}
+	c.Government = strings.Join(strings.Fields(strings.Join(lines, " ")), " ")
+	if c.Monarchy = reMonarchy.MatchString(c.Government); c.Monarchy {
+		c.Tags = append(c.Tags, "monarchy")
+	}
+	return nil
+}
+
+// makeMonarchyLists renders the remaining monarchies per continent.
+func makeMonarchyLists(countries []Country) error {
+	monarchies := make(map[string][]string)
+	for _, c := range countries {
+		if !c.Monarchy {
+			continue
+		}
+		for _, continent := range c.Continents {
+			monarchies[continent] = append(monarchies[continent], c.Name)
+		}
+	}
+
+	dir := filepath.Join("countries", "government", "monarchies")
+	for _, continent := range sortedKeys(monarchies) {
+		names := monarchies[continent]
+		sort.Strings(names)
+		l := CountryList{
+			Question:  fmt.Sprintf("Name the monarchies of **%s**.", continent),
+			Countries: names,
+		}
+		if err := makeTmpl(dir, "monarchies_"+toURLName(continent), "country-list", &l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+)
+
+const hdiPage = "List_of_countries_by_Human_Development_Index"
+
+// HDI tiers by minimum index, as defined by the UNDP.
+var hdiTiers = []struct {
+	min  float64
+	name string
+}{
+	{0.800, "Very high"},
+	{0.700, "High"},
+	{0.550, "Medium"},
+	{0, "Low"},
+}
+
+func hdiTier(hdi float64) string {
+	for _, t := range hdiTiers {
+		if hdi >= t.min {
+			return t.name
+		}
+	}
+	return ""
+}
+
+// hdiTable is the index by country name, scraped once per run.
+var hdiTable struct {
+	sync.Mutex
+	values map[string]float64
+	err    error
+}
+
+// parseHDI parses an index cell, e.g. "0.903", rejecting ranks and changes.
+func parseHDI(s string) (float64, bool) {
+	if !strings.HasPrefix(s, "0.") && s != "1.000" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// getHDIs scrapes the index of each country from the list page's
+// wikitables, taking the first index cell after the country. Later tables
+// list past values so the first found is kept.
+func getHDIs(refresh bool) (map[string]float64, error) {
+	hdiTable.Lock()
+	defer hdiTable.Unlock()
+	if hdiTable.values != nil || hdiTable.err != nil {
+		return hdiTable.values, hdiTable.err
+	}
+	page, err := getLockedWikiPage(wikipedia, hdiPage, refresh)
+	if err != nil {
+		hdiTable.err = err
+		return nil, err
+	}
+	values := make(map[string]float64)
+	for _, row := range parseWikiTables(page.Revisions[0].Text) {
+		for i, cell := range row {
+			// Skip ranks and rank changes to the country.
+			if strings.IndexFunc(cell, unicode.IsLetter) == -1 {
+				continue
+			}
+			if _, seen := values[cell]; !seen {
+				for _, next := range row[i+1:] {
+					if v, ok := parseHDI(next); ok {
+						values[cell] = v
+						break
+					}
+				}
+			}
+			break
+		}
+	}
+	if len(values) == 0 {
+		hdiTable.err = fmt.Errorf("no index values found in %s", hdiPage)
+		return nil, hdiTable.err
+	}
+	hdiTable.values = values
+	return values, nil
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("hdi").Parse(`{{template "front-matter" front nil .Tags}}What is the Human Development Index tier of **{{.Name}}**?
+<!--question-->
+**{{.HDITier}}** *({{printf "%.3f" .HDI}})*`))
+}
+
+// extractHDI sets the index of the country from the list page, matching the
+// country or its page title.
+func extractHDI(c *Country, src *Source) error {
+	values, err := getHDIs(src.Refresh)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{c.Name, c.Title} {
+		if v, ok := values[name]; ok {
+			c.HDI, c.HDITier = v, hdiTier(v)
+			return nil
+		}
+	}
+	report.fallback(c.UName, "hdi")
+	return nil
+}
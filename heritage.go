@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+)
+
+// Heritage lists the UNESCO World Heritage sites of a country.
+type Heritage struct {
+	Provenance
+
+	Country   string `json:"-"` // set when rendered, per --name-style
+	Sites     []string
+	ImageName string // featured site
+	ImageFile string // commons file name of the featured site, empty if none
+	ImageURL  string `json:"-"` // set when rendered
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("heritage").Parse(`Name a UNESCO World Heritage site in **{{.Country}}**.
+<!--question-->
+{{range .Sites}}- {{.}}
+{{end}}{{if .ImageURL}}
+![{{.ImageName}}]({{.ImageURL}}){{end}}`))
+}
+
+// Sites designated (P1435) World Heritage Site (Q9259) in the country.
+const heritageQuery = `SELECT ?siteLabel ?image WHERE {
+  ?site wdt:P1435 wd:Q9259 ;
+        wdt:P17 wd:%s .
+  OPTIONAL { ?site wdt:P18 ?image . }
+  SERVICE wikibase:label { bd:serviceParam wikibase:language "en". }
+}`
+
+// getHeritage returns the sites of the country, featuring the first with an
+// image.
+func getHeritage(uname string, refresh bool) (*Heritage, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := querySPARQL(uname+"_heritage", fmt.Sprintf(heritageQuery, e.ID), refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query results are unordered, prefer the first image by name.
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["image"] < rows[j]["image"] })
+
+	h := &Heritage{}
+	h.Article = pageURL(wikidata, e.ID)
+	images := make(map[string]string)
+	for _, row := range rows {
+		site := row["siteLabel"]
+		if _, ok := images[site]; !ok {
+			h.Sites = append(h.Sites, site)
+		}
+		if images[site] == "" {
+			images[site] = row["image"]
+		}
+	}
+	sort.Strings(h.Sites)
+	for _, site := range h.Sites {
+		if image := images[site]; image != "" {
+			h.ImageName = site
+			h.ImageFile = commonsFileName(image)
+			h.Files = []string{filePageURL(h.ImageFile)}
+			break
+		}
+	}
+	return h, nil
+}
+
+type heritageCards struct{}
+
+func (heritageCards) Name() string  { return "heritage" }
+func (heritageCards) Enabled() bool { return *flagHeritage }
+func (heritageCards) Extract(c *Country, src *Source) (err error) {
+	c.Heritage, err = getHeritage(c.UName, src.Refresh)
+	return err
+}
+func (heritageCards) Render(c *Country, src *Source) error {
+	return makeHeritage(c, deckDir(c, "heritage"), src.Refresh)
+}
+
+func init() {
+	registerCardType(heritageCards{})
+}
+
+func makeHeritage(c *Country, dir string, refresh bool) error {
+	if c.Heritage == nil || len(c.Heritage.Sites) == 0 {
+		return nil
+	}
+	h := *c.Heritage
+	h.Country = c.Name
+	if h.ImageFile != "" {
+		if err := makeImage(dir, h.ImageFile, refresh); err != nil {
+			return err
+		}
+		h.ImageURL = imageURL(h.ImageFile)
+	}
+	return makeTmpl(dir, countryFileName(c), "heritage", &h)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeritage(t *testing.T) {
+	f := &memFetcher{Queries: map[string][]byte{
+		filepath.Join("pages", wikidataQuery, "Peru_heritage.json"): []byte(`{"results":{"bindings":[
+{"siteLabel":{"value":"Nazca Lines"}},
+{"siteLabel":{"value":"Machu Picchu"},"image":{"value":"http://commons.wikimedia.org/wiki/Special:FilePath/Machu%20Picchu.jpg"}},
+{"siteLabel":{"value":"Chan Chan"},"image":{"value":"http://commons.wikimedia.org/wiki/Special:FilePath/Chan%20Chan.jpg"}}
+]}}`),
+	}}
+	f.addEntity("Q419", "Peru", nil, "Peru")
+	counting := &countingFetcher{Fetcher: f, files: make(map[string]int)}
+	withFetcher(t, counting)
+
+	c := &Country{Name: "Peru", UName: "Peru"}
+	src := &Source{}
+	if err := (heritageCards{}).Extract(c, src); err != nil {
+		t.Fatal(err)
+	}
+	h := c.Heritage
+	if want := []string{"Chan Chan", "Machu Picchu", "Nazca Lines"}; !reflect.DeepEqual(h.Sites, want) {
+		t.Errorf("sites %q, want %q", h.Sites, want)
+	}
+	if h.ImageName != "Chan Chan" || h.ImageFile != "Chan_Chan.jpg" {
+		t.Errorf("featured %q %q, want Chan Chan", h.ImageName, h.ImageFile)
+	}
+	if len(counting.files) > 0 {
+		t.Errorf("extract downloaded %v", counting.files)
+	}
+
+	withFetcher(t, &memFetcher{Files: map[string][]byte{"Chan_Chan.jpg": []byte("\xff\xd8\xff\xe0")}})
+	c.Name = "Republic of Peru"
+	if err := (heritageCards{}).Render(c, src); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(deckDir(c, "heritage"), "Peru.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "Name a UNESCO World Heritage site in **Republic of Peru**.") ||
+		!strings.Contains(string(b), "![Chan Chan](images/Chan_Chan.jpg)") {
+		t.Errorf("heritage card:\n%s", b)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const historicalFlagsFile = "historical_flags.txt"
+
+// HistoricalFlag is a curated former flag and the modern countries or region
+// it corresponds to.
+type HistoricalFlag struct {
+	Provenance
+
+	FileName string // commons file name
+	State    string // former state, e.g. Soviet Union
+	Years    string // years in use, e.g. 1922–1991
+	Modern   string
+	ImageURL string
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("historical-flag").Parse(`Which modern country or region does this former flag correspond to?
+
+![Former flag]({{.ImageURL}})
+<!--question-->
+**{{.Modern}}** *({{.State}}, {{.Years}})*`))
+}
+
+// readHistoricalFlags parses the curated list, one flag per line as
+// "file | state | years | modern", skipping blank lines and # comments.
+func readHistoricalFlags(path string) ([]HistoricalFlag, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var flags []HistoricalFlag
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s:%d: want 4 fields, got %d", path, i+1, len(fields))
+		}
+		for j := range fields {
+			fields[j] = strings.TrimSpace(fields[j])
+		}
+		flags = append(flags, HistoricalFlag{
+			FileName: toURLName(fields[0]),
+			State:    fields[1],
+			Years:    fields[2],
+			Modern:   fields[3],
+		})
+	}
+	return flags, nil
+}
+
+// makeHistoricalFlags renders the curated former flags deck.
+func makeHistoricalFlags(refresh bool) error {
+	flags, err := readHistoricalFlags(historicalFlagsFile)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join("countries", "historical_flags")
+	for _, f := range flags {
+		if err := makeFlagImage(dir, f.FileName, refresh); err != nil {
+			return fmt.Errorf("%s: %w", f.State, err)
+		}
+		f.ImageURL = imageURL(f.FileName)
+		f.Files = []string{filePageURL(f.FileName)}
+		if err := makeTmpl(dir, toURLName(f.State), "historical-flag", &f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
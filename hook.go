@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// postRender passes the card or media file through the --post-render
+// command before it is written: the content is its stdin, its stdout
+// replaces the content, and POST_RENDER_KIND (card or media) and
+// POST_RENDER_PATH are set, e.g. to watermark images or add card fields.
+func postRender(kind, path string, b []byte) ([]byte, error) {
+	if *flagPostRender == "" {
+		return b, nil
+	}
+	cmd := exec.Command("sh", "-c", *flagPostRender)
+	cmd.Env = append(os.Environ(), "POST_RENDER_KIND="+kind, "POST_RENDER_PATH="+path)
+	cmd.Stdin = bytes.NewReader(b)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("post-render %s: %w", path, err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// <svg ...>
+	reSVGRoot = regexp.MustCompile(`(?s)<svg\b[^>]*>`)
+
+	// width="900", viewBox="0 0 900 600"
+	reSVGAttr = regexp.MustCompile(`\s(width|height|x|y|viewBox)\s*=\s*["']([^"']*)["']`)
+
+	// <?xml ...?>, <!DOCTYPE ...>
+	reSVGProlog = regexp.MustCompile(`(?s)<\?xml.*?\?>|<!DOCTYPE[^>]*>`)
+)
+
+// parseAspect parses a ratio such as "3:2".
+func parseAspect(s string) (float64, error) {
+	ss := strings.Split(s, ":")
+	if len(ss) != 2 {
+		return 0, fmt.Errorf("invalid aspect ratio %q", s)
+	}
+	w, err := strconv.ParseFloat(ss[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid aspect ratio %q: %w", s, err)
+	}
+	h, err := strconv.ParseFloat(ss[1], 64)
+	if err != nil || h == 0 {
+		return 0, fmt.Errorf("invalid aspect ratio %q", s)
+	}
+	return w / h, nil
+}
+
+// letterbox returns the canvas size and offset to fit w x h in the aspect.
+func letterbox(w, h, aspect float64) (cw, ch, x, y float64) {
+	if w/h > aspect {
+		ch = w / aspect
+		return w, ch, 0, (ch - h) / 2
+	}
+	cw = h * aspect
+	return cw, h, (cw - w) / 2, 0
+}
+
+// padSVG nests the svg document in a transparent canvas of the aspect.
+func padSVG(b []byte, aspect float64) ([]byte, error) {
+	b = reSVGProlog.ReplaceAll(b, nil)
+	loc := reSVGRoot.FindIndex(b)
+	if loc == nil {
+		return nil, fmt.Errorf("missing svg root")
+	}
+	root := string(b[loc[0]:loc[1]])
+
+	attrs := make(map[string]string)
+	for _, v := range reSVGAttr.FindAllStringSubmatch(root, -1) {
+		attrs[v[1]] = v[2]
+	}
+	var w, h float64
+	viewBox := attrs["viewBox"]
+	if vs := strings.Fields(strings.Replace(viewBox, ",", " ", -1)); len(vs) == 4 {
+		w, _ = strconv.ParseFloat(vs[2], 64)
+		h, _ = strconv.ParseFloat(vs[3], 64)
+	} else {
+		w, _ = strconv.ParseFloat(strings.TrimSuffix(attrs["width"], "px"), 64)
+		h, _ = strconv.ParseFloat(strings.TrimSuffix(attrs["height"], "px"), 64)
+		viewBox = fmt.Sprintf("0 0 %g %g", w, h)
+	}
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("unknown svg size")
+	}
+	cw, ch, x, y := letterbox(w, h, aspect)
+
+	root = reSVGAttr.ReplaceAllString(root, "")
+	root = strings.TrimSuffix(root, ">")
+	root = fmt.Sprintf(`%s x="%g" y="%g" width="%g" height="%g" viewBox="%s">`, root, x, y, w, h, viewBox)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">
+`, cw, ch, cw, ch)
+	buf.Write(bytes.TrimSpace(b[:loc[0]]))
+	buf.WriteString(root)
+	buf.Write(b[loc[1]:])
+	buf.WriteString("\n</svg>\n")
+	return buf.Bytes(), nil
+}
+
+// padPNG draws the image centered on a transparent canvas of the aspect.
+func padPNG(b []byte, aspect float64) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	size := src.Bounds().Size()
+	cw, ch, x, y := letterbox(float64(size.X), float64(size.Y), aspect)
+	dst := image.NewRGBA(image.Rect(0, 0, int(cw+0.5), int(ch+0.5)))
+	at := image.Pt(int(x+0.5), int(y+0.5))
+	draw.Draw(dst, src.Bounds().Sub(src.Bounds().Min).Add(at), src, src.Bounds().Min, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// makeFlagImage stores the flag image, padding it to the --pad-flags aspect
+// ratio if set.
+func makeFlagImage(dir, name string, refresh bool) error {
+	if *flagPadFlags == "" || *flagRemoteImages {
+		return makeImage(dir, name, refresh)
+	}
+	aspect, err := parseAspect(*flagPadFlags)
+	if err != nil {
+		return err
+	}
+	r, err := getFile(name, refresh)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := lock.file(name, b); err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".svg":
+		b, err = padSVG(b, aspect)
+	case ".png":
+		b, err = padPNG(b, aspect)
+	default:
+		return makeImage(dir, name, refresh)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return writeMedia(filepath.Join(dir, "images", localName(name)), b)
+}
+
+// Countries too small to see on an orthographic map.
+var microstates = map[string]bool{
+	"Andorra":       true,
+	"Liechtenstein": true,
+	"Luxembourg":    true,
+	"Malta":         true,
+	"Monaco":        true,
+	"San_Marino":    true,
+	"Singapore":     true,
+	"Vatican_City":  true,
+}
+
+// zoomMaxArea in km² below which countries get a zoomed map.
+const zoomMaxArea = 5000
+
+// needsZoomMap reports whether the country is too small for the standard map.
+func needsZoomMap(uname string, refresh bool) (bool, error) {
+	if microstates[uname] {
+		return true, nil
+	}
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return false, err
+	}
+	if vs := e.Quantities("P2046"); len(vs) > 0 && vs[0] < zoomMaxArea { // area
+		return true, nil
+	}
+	return hasID(e.ItemIDs("P31"), qIslandNation), nil // instance of
+}
+
+// findZoomMap tries the other infobox maps and then common commons naming
+// patterns for a zoomed map, empty if none exist. Candidates are probed
+// without downloading them.
+func findZoomMap(text, name, mapName string, refresh bool) (string, error) {
+	var candidates []string
+	for _, m := range infoboxMaps(text) {
+		if len(m.msgs) == 0 {
+			candidates = append(candidates, m.name)
+		}
+	}
+	uname := toURLName(name)
+	candidates = append(candidates,
+		uname+"_on_the_globe_("+uname+"_centered).svg",
+		uname+"_in_its_region.svg",
+	)
+	for _, c := range candidates {
+		if c == "" || c == mapName {
+			continue
+		}
+		ok, err := fileExists(c, refresh)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return c, nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestFindZoomMap(t *testing.T) {
+	f := &countingFetcher{
+		Fetcher: &memFetcher{Queries: map[string][]byte{
+			existsKey("Malta_on_the_globe_(Malta_centered).svg"): []byte(`{"query":{"pages":[{"title":"File:Malta on the globe (Malta centered).svg","missing":true}]}}`),
+			existsKey("Malta_in_its_region.svg"):                 []byte(`{"query":{"pages":[{"pageid":1,"title":"File:Malta in its region.svg"}]}}`),
+		}},
+		files: make(map[string]int),
+	}
+	withFetcher(t, f)
+	name, err := findZoomMap("", "Malta", "Malta_(orthographic_projection).svg", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "Malta_in_its_region.svg" {
+		t.Errorf("zoom map %q, want Malta_in_its_region.svg", name)
+	}
+	if len(f.files) > 0 {
+		t.Errorf("probing downloaded %v", f.files)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const indexFile = "index.md"
+
+// DeckIndex is the browsable table of a deck directory's cards.
+type DeckIndex struct {
+	Deck      string
+	Rows      []IndexRow
+	Countries int
+	Images    int // cards with an image
+}
+
+type IndexRow struct {
+	Country string
+	Type    string // card type, e.g. flags
+	File    string
+	Image   string // first image reference, empty if none
+}
+
+var indexTmpl = template.Must(template.New("index").Funcs(template.FuncMap{
+	"cell": func(s string) string { return strings.Replace(s, "|", `\|`, -1) },
+}).Parse(`# {{.Deck}}
+
+{{len .Rows}} cards of {{.Countries}} countries, {{.Images}} with images.
+
+| Country | Type | Card | Image |
+|---|---|---|---|
+{{range .Rows}}| {{cell .Country}} | {{.Type}} | [{{cell .File}}](<{{.File}}>) |{{if .Image}} [image](<{{.Image}}>){{end}} |
+{{end}}`))
+
+// cardImage returns the first image reference of the generated card.
+func cardImage(path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	if v := reMarkdownImage.FindStringSubmatch(string(b)); v != nil {
+		return v[2]
+	}
+	return ""
+}
+
+// makeIndexes writes an index.md per deck directory tabling its country
+// cards, for browsing on GitHub or in a file manager.
+func makeIndexes(countries []Country) error {
+	indexes := make(map[string]*DeckIndex)
+	seen := make(map[string]map[string]bool) // countries by dir
+	for _, t := range enabledCardTypes() {
+		ct, ok := t.(*countryCards)
+		if !ok || ct.tmpl == "" {
+			continue
+		}
+		for i := range countries {
+			c := &countries[i]
+			if ct.has != nil && !ct.has(c) {
+				continue
+			}
+			dir, name := cardPath(c, ct.deck(), countryFileName(c)+ct.suffix, ct.tmpl)
+			idx, ok := indexes[dir]
+			if !ok {
+				deck, err := filepath.Rel("countries", dir)
+				if err != nil {
+					return err
+				}
+				idx = &DeckIndex{Deck: deckFileName(deck)}
+				indexes[dir] = idx
+				seen[dir] = make(map[string]bool)
+			}
+			file := cardName(name) + ".md"
+			row := IndexRow{
+				Country: c.Name,
+				Type:    ct.name,
+				File:    file,
+				Image:   cardImage(filepath.Join(dir, file)),
+			}
+			if row.Image != "" {
+				idx.Images++
+			}
+			if !seen[dir][c.Name] {
+				seen[dir][c.Name] = true
+				idx.Countries++
+			}
+			idx.Rows = append(idx.Rows, row)
+		}
+	}
+
+	for dir, idx := range indexes {
+		sort.SliceStable(idx.Rows, func(i, j int) bool { return idx.Rows[i].Country < idx.Rows[j].Country })
+		var buf bytes.Buffer
+		if err := indexTmpl.Execute(&buf, idx); err != nil {
+			return err
+		}
+		if err := writeOutput(filepath.Join(dir, indexFile), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// reLinkTarget matches the page of the first wiki link.
+var reLinkTarget = regexp.MustCompile(`\[\[([^\]|#]+)`)
+
+func init() {
+	tmpls = template.Must(tmpls.New("largest-city").Parse(`{{template "front-matter" front nil .Tags}}Is {{.Capital}}, the capital of **{{.Name}}**, its largest city?
+<!--question-->
+{{if eq .LargestCity .Capital}}**Yes**{{if .CapitalPopulation}} *(population {{int .CapitalPopulation}})*{{end}}{{else}}**No**, the largest city is **{{.LargestCity}}**{{if and .LargestCityPopulation .CapitalPopulation}} *(population {{int .LargestCityPopulation}}, the capital {{int .CapitalPopulation}})*{{end}}{{end}}`))
+}
+
+// extractLargestCity parses the infobox largest city, "capital" when it is
+// the capital, and the city populations from wikidata. Countries with
+// several capitals are skipped as the question is ambiguous.
+func extractLargestCity(c *Country, src *Source) error {
+	field := infoboxField(src.Text(), "largest_city")
+	if field == "" || strings.Contains(c.Capital, " and ") {
+		report.fallback(c.UName, "largest city")
+		return nil
+	}
+	e, err := getEntity(c.UName, src.Refresh)
+	if err != nil {
+		return err
+	}
+	if ids := e.ItemIDs("P36"); len(ids) > 0 { // capital
+		capital, err := getEntityByID(ids[0], src.Refresh)
+		if err != nil {
+			return err
+		}
+		c.CapitalPopulation, _ = capital.LatestQuantity("P1082") // population
+	}
+
+	if strings.HasPrefix(strings.ToLower(cleanWikiText(field)), "capital") {
+		c.LargestCity, c.LargestCityPopulation = c.Capital, c.CapitalPopulation
+		return nil
+	}
+	v := reLinkTarget.FindStringSubmatch(field)
+	if v == nil {
+		report.warnf(c.UName, "largest city: no link in %q", field)
+		report.fallback(c.UName, "largest city")
+		return nil
+	}
+	uname := toURLName(strings.TrimSpace(v[1]))
+	c.LargestCity = strings.Replace(uname, "_", " ", -1)
+	if lines := infoboxLines(src.Text(), "largest_city"); len(lines) > 0 {
+		c.LargestCity = lines[0]
+	}
+	if c.LargestCity == c.Capital || uname == toURLName(c.Capital) {
+		c.LargestCity, c.LargestCityPopulation = c.Capital, c.CapitalPopulation
+		return nil
+	}
+	city, err := getEntity(uname, src.Refresh)
+	if err != nil {
+		report.warnf(c.UName, "largest city: %v", err)
+		return nil
+	}
+	c.LargestCityPopulation, _ = city.LatestQuantity("P1082")
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// hostRates are the default requests per second per host. Images on
+// upload.wikimedia.org tolerate far more than the APIs.
+var hostRates = map[string]float64{
+	wikipedia:              1,
+	wiktionary:             1,
+	wikidata:               2,
+	wikidataQuery:          0.5,
+	commons:                1,
+	"upload.wikimedia.org": 5,
+}
+
+// defaultRate applies to hosts without a configured rate.
+const defaultRate = 1
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*rate.Limiter)
+)
+
+// setupLimiters applies the --rates overrides, e.g.
+// "upload.wikimedia.org=10,www.wikidata.org=1".
+func setupLimiters() error {
+	for _, v := range strings.Split(*flagRates, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		i := strings.Index(v, "=")
+		if i < 0 {
+			return fmt.Errorf("invalid rate %q, want host=requests per second", v)
+		}
+		r, err := strconv.ParseFloat(v[i+1:], 64)
+		if err != nil || r <= 0 {
+			return fmt.Errorf("invalid rate %q, want host=requests per second", v)
+		}
+		hostRates[v[:i]] = r
+	}
+	return nil
+}
+
+// limiter returns the rate limiter of the url's host.
+func limiter(rawurl string) *rate.Limiter {
+	host := rawurl
+	if u, err := url.Parse(rawurl); err == nil {
+		host = u.Host
+	}
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l, ok := limiters[host]
+	if !ok {
+		r, ok := hostRates[host]
+		if !ok {
+			r = defaultRate
+		}
+		l = rate.NewLimiter(rate.Limit(r), 2)
+		limiters[host] = l
+	}
+	return l
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// <audio controls src="path">
+var reSrcRef = regexp.MustCompile(`src="([^"]+)"`)
+
+// checkLinks verifies every media reference in the generated markdown under
+// root exists, remote references are requested.
+func checkLinks(root string) error {
+	var broken []string
+	checked := make(map[string]error)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var refs []string
+		for _, v := range reMarkdownImage.FindAllStringSubmatch(string(b), -1) {
+			refs = append(refs, v[2])
+		}
+		for _, v := range reSrcRef.FindAllStringSubmatch(string(b), -1) {
+			refs = append(refs, v[1])
+		}
+
+		for _, ref := range refs {
+			if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") {
+				err, ok := checked[ref]
+				if !ok {
+					err = head(ref)
+					checked[ref] = err
+				}
+				if err != nil {
+					broken = append(broken, fmt.Sprintf("%s: %v", path, err))
+				}
+				continue
+			}
+			name, err := url.PathUnescape(ref)
+			if err != nil {
+				name = ref
+			}
+			if _, err := os.Stat(filepath.Join(filepath.Dir(path), name)); err != nil {
+				broken = append(broken, fmt.Sprintf("%s: missing %s", path, ref))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(broken) > 0 {
+		return fmt.Errorf("%d broken links:\n%s", len(broken), strings.Join(broken, "\n"))
+	}
+	fmt.Println("links ok")
+	return nil
+}
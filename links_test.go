@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckLinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "deck-countries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	images := filepath.Join(dir, "images")
+	if err := os.Mkdir(images, 0755); err != nil {
+		t.Fatal(err)
+	}
+	name := "Afghanistan_(orthographic_projection).svg"
+	if err := ioutil.WriteFile(filepath.Join(images, name), []byte("<svg/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	card := "![Afghanistan](images/" + name + ")\n"
+	path := filepath.Join(dir, "Afghanistan.md")
+	if err := ioutil.WriteFile(path, []byte(card), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkLinks(dir); err != nil {
+		t.Fatalf("checkLinks: %v", err)
+	}
+
+	card += "![Ghana](images/Ghana_(orthographic_projection).svg)\n"
+	if err := ioutil.WriteFile(path, []byte(card), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = checkLinks(dir)
+	if err == nil {
+		t.Fatal("checkLinks: missing image not reported")
+	}
+	if want := "missing images/Ghana_(orthographic_projection).svg"; !strings.Contains(err.Error(), want) {
+		t.Errorf("checkLinks: %v, want %q", err, want)
+	}
+	if strings.Contains(err.Error(), "Afghanistan_") {
+		t.Errorf("checkLinks: %v, reported an existing image", err)
+	}
+}
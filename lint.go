@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// lintTemplates checks at startup that the templates of the enabled card
+// types exist and only reference fields of the Country model, and that the
+// overrides name known fields, so mistakes fail fast rather than mid-run.
+func lintTemplates() error {
+	var errs []string
+	country := reflect.TypeOf(&Country{})
+	for _, t := range enabledCardTypes() {
+		ct, ok := t.(*countryCards)
+		if !ok || ct.tmpl == "" {
+			continue
+		}
+		tmpl := tmpls.Lookup(ct.tmpl)
+		if tmpl == nil {
+			errs = append(errs, fmt.Sprintf("%s: template %q not defined", ct.name, ct.tmpl))
+			continue
+		}
+		l := templateLinter{tmpl: tmpl, root: country}
+		l.walk(tmpl.Tree.Root, country)
+		for _, e := range l.errs {
+			errs = append(errs, ct.name+": "+e)
+		}
+	}
+
+	overrides.mu.Lock()
+	for uname, fields := range overrides.fields {
+		for field := range fields {
+			if !hasString(overrideFields, field) {
+				errs = append(errs, fmt.Sprintf("%s: %s: unknown override field %q, want one of %s", overridesFile, uname, field, strings.Join(overrideFields, ", ")))
+			}
+		}
+	}
+	overrides.mu.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("lint:\n\t%s", strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
+
+// templateLinter checks the field references of a template against the type
+// of dot, which changes inside range and with. Unresolvable types, e.g.
+// interfaces, are not checked.
+type templateLinter struct {
+	tmpl *template.Template
+	root reflect.Type
+	errs []string
+}
+
+func (l *templateLinter) errorf(n parse.Node, format string, args ...interface{}) {
+	loc, _ := l.tmpl.ErrorContext(n)
+	l.errs = append(l.errs, loc+": "+fmt.Sprintf(format, args...))
+}
+
+func (l *templateLinter) walk(n parse.Node, dot reflect.Type) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			l.walk(c, dot)
+		}
+	case *parse.ActionNode:
+		l.pipe(n.Pipe, dot)
+	case *parse.TemplateNode:
+		l.pipe(n.Pipe, dot) // the called template is checked on its own dot
+	case *parse.IfNode:
+		l.branch(&n.BranchNode, dot, dot)
+	case *parse.WithNode:
+		l.branch(&n.BranchNode, dot, l.pipeType(n.Pipe, dot))
+	case *parse.RangeNode:
+		l.branch(&n.BranchNode, dot, elemType(l.pipeType(n.Pipe, dot)))
+	}
+}
+
+func (l *templateLinter) branch(b *parse.BranchNode, dot, inner reflect.Type) {
+	l.pipe(b.Pipe, dot)
+	l.walk(b.List, inner)
+	l.walk(b.ElseList, dot)
+}
+
+func (l *templateLinter) pipe(p *parse.PipeNode, dot reflect.Type) {
+	if p == nil {
+		return
+	}
+	for _, cmd := range p.Cmds {
+		for _, arg := range cmd.Args {
+			l.arg(arg, dot)
+		}
+	}
+}
+
+func (l *templateLinter) arg(n parse.Node, dot reflect.Type) {
+	switch n := n.(type) {
+	case *parse.FieldNode:
+		l.fields(n, dot, n.Ident)
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" && len(n.Ident) > 1 {
+			l.fields(n, l.root, n.Ident[1:])
+		}
+	case *parse.PipeNode:
+		l.pipe(n, dot)
+	}
+}
+
+func (l *templateLinter) fields(n parse.Node, t reflect.Type, idents []string) {
+	for _, ident := range idents {
+		if t == nil {
+			return
+		}
+		next, ok := fieldType(t, ident)
+		if !ok {
+			l.errorf(n, "field %s not in %s", ident, strings.TrimPrefix(strings.TrimPrefix(t.String(), "*"), "main."))
+			return
+		}
+		t = next
+	}
+}
+
+// fieldType returns the type of the field or method of t, nil if it can't be
+// resolved statically.
+func fieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	if m, ok := t.MethodByName(name); ok {
+		if m.Type.NumOut() == 0 {
+			return nil, true
+		}
+		return m.Type.Out(0), true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if f, ok := t.FieldByName(name); ok {
+			return f.Type, true
+		}
+		if m, ok := reflect.PtrTo(t).MethodByName(name); ok && m.Type.NumOut() > 0 {
+			return m.Type.Out(0), true
+		}
+		return nil, false
+	case reflect.Map:
+		return t.Elem(), true
+	}
+	return nil, true
+}
+
+// pipeType returns the type of a pipeline of a single field, nil otherwise.
+func (l *templateLinter) pipeType(p *parse.PipeNode, dot reflect.Type) reflect.Type {
+	if p == nil || len(p.Cmds) != 1 || len(p.Cmds[0].Args) != 1 {
+		return nil
+	}
+	f, ok := p.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok {
+		return nil
+	}
+	t := dot
+	for _, ident := range f.Ident {
+		if t == nil {
+			return nil
+		}
+		t, _ = fieldType(t, ident)
+	}
+	return t
+}
+
+// elemType returns the element type ranged over, nil if unknown.
+func elemType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return t.Elem()
+	}
+	return nil
+}
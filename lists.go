@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+var (
+	// [[Target|Text]] or [[Text]]
+	reWikiLink = regexp.MustCompile(`\[\[(?:[^\]|]*\|)?([^\]|]*)\]\]`)
+
+	// {{lang|fr|Text}}, {{lang-fr|Text}}, {{small|Text}}, {{flag|Text}}
+	reTextTemplate = regexp.MustCompile(`(?i){{\s*(?:lang\|[^|{}]*|lang-[^|{}]*|small|nowrap|nobold|big|flag|flagcountry|flagu)\|([^|{}]*)[^{}]*}}`)
+
+	// {{template|...}}, innermost first.
+	reWikiTemplate = regexp.MustCompile(`{{[^{}]*}}`)
+
+	// <ref>...</ref>, <ref name="x"/>, <ref name="a/b"/>
+	reWikiRef = regexp.MustCompile(`(?s)<ref\b[^>]*?/>|<ref\b[^>]*>.*?</ref>`)
+
+	// <!-- comment -->
+	reWikiComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+
+	// <code>, </span>, ...
+	reHTMLTag = regexp.MustCompile(`<[^>]+>`)
+
+	reCode3 = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	// Tokyo, São Paulo, Xi'an, K2
+	reProperName = regexp.MustCompile(`^\p{Lu}[\p{L}\p{M}\p{N} '.-]+$`)
+
+	// D, CH, GBZ
+	reVehicleCode = regexp.MustCompile(`^[A-Z]{1,3}$`)
+)
+
+// cleanWikiText reduces a fragment of wikitext to its displayed text.
+func cleanWikiText(s string) string {
+	s = reWikiRef.ReplaceAllString(s, "")
+	s = reWikiLink.ReplaceAllString(s, "$1")
+	s = reTextTemplate.ReplaceAllString(s, "$1")
+	for reWikiTemplate.MatchString(s) {
+		s = reWikiTemplate.ReplaceAllString(s, "")
+	}
+	s = reHTMLTag.ReplaceAllString(s, "")
+	s = strings.Replace(s, "'''", "", -1)
+	s = strings.Replace(s, "''", "", -1)
+	return strings.TrimSpace(s)
+}
+
+// cleanWikiMarkup strips comments, refs and HTML tags and decodes HTML
+// entities, e.g. "&amp;". Unbalanced tags are cut and returned as warnings.
+func cleanWikiMarkup(s string) (string, []string) {
+	var warnings []string
+	s = reWikiComment.ReplaceAllString(s, "")
+	s = reWikiRef.ReplaceAllString(s, "")
+	s = reHTMLTag.ReplaceAllString(s, "")
+	if i := strings.IndexAny(s, "<>"); i > -1 {
+		warnings = append(warnings, fmt.Sprintf("unbalanced tag in %q", s))
+		s = s[:i]
+	}
+	return html.UnescapeString(s), warnings
+}
+
+// parseWikiTables returns the cleaned cells of every row of the wikitables
+// in the text, header rows are skipped.
+func parseWikiTables(text string) [][]string {
+	rows := wikiTableRows(text)
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = cleanWikiCell(cell)
+		}
+	}
+	return rows
+}
+
+// wikiTableRows returns the wikitext cells of every row of the wikitables in
+// the text, header rows are skipped.
+func wikiTableRows(text string) [][]string {
+	var rows [][]string
+	for _, table := range strings.Split(text, "{|")[1:] {
+		if i := strings.Index(table, "\n|}"); i > -1 {
+			table = table[:i]
+		}
+		for _, row := range strings.Split(table, "\n|-")[1:] {
+			var cells []string
+			header := true
+			for _, line := range strings.Split(row, "\n") {
+				if !strings.HasPrefix(line, "|") && !strings.HasPrefix(line, "!") {
+					continue
+				}
+				header = header && line[0] == '!'
+				line = line[1:]
+				line = strings.Replace(line, "!!", "||", -1)
+				for _, cell := range strings.Split(line, "||") {
+					cells = append(cells, cell)
+				}
+			}
+			if len(cells) > 0 && !header {
+				rows = append(rows, cells)
+			}
+		}
+	}
+	return rows
+}
+
+// cleanWikiCell drops any cell attributes, e.g. `style="..." | Text`. Line
+// breaks become commas.
+func cleanWikiCell(s string) string {
+	s = reWikiLink.ReplaceAllString(s, "$1")
+	s = reBreak.ReplaceAllString(s, ", ")
+	if i := strings.LastIndex(s, "|"); i > -1 && !strings.Contains(s[i:], "}}") {
+		s = s[i+1:]
+	}
+	return cleanWikiText(s)
+}
+
+// splitList splits a cell listing several values, e.g. "China, Pakistan" or
+// "China / Pakistan".
+func splitList(s string) []string {
+	var vs []string
+	for _, v := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '/' || r == ';' }) {
+		if v = strings.TrimSpace(v); v != "" {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// ListSpec scrapes a key to answer deck, e.g. codes to countries, from the
+// wikitables of a wikipedia page.
+type ListSpec struct {
+	Name     string // deck name
+	Dir      string // deck directory under countries, defaults to codes/<name>
+	Page     string
+	Question string // formatted with the code
+	Key      int    // column of the code
+	Value    int    // column of the country
+	Pattern  *regexp.Regexp
+	Limit    *int // maximum cards in page order, nil for all
+	Multi    bool // the value lists several answers, e.g. countries
+}
+
+var listSpecs = []ListSpec{{
+	Name:     "ioc",
+	Page:     "List_of_IOC_country_codes",
+	Question: "Which country has the IOC code **%s**?",
+	Key:      0,
+	Value:    1,
+	Pattern:  reCode3,
+}, {
+	Name:     "fifa",
+	Page:     "List_of_FIFA_country_codes",
+	Question: "Which country has the FIFA code **%s**?",
+	Key:      1,
+	Value:    0,
+	Pattern:  reCode3,
+}, {
+	Name:     "vehicle",
+	Page:     "International_vehicle_registration_code",
+	Question: "Which country uses the vehicle registration code **%s**?",
+	Key:      0,
+	Value:    1,
+	Pattern:  reVehicleCode,
+}, {
+	Name:     "cities",
+	Dir:      "cities",
+	Page:     "List_of_largest_cities",
+	Question: "Which country is **%s** in?",
+	Key:      0,
+	Value:    1,
+	Pattern:  reProperName,
+	Limit:    flagTopCities,
+}, {
+	Name:     "rivers",
+	Dir:      "rivers",
+	Page:     "List_of_river_systems_by_length",
+	Question: "Which countries does the **%s** flow through?",
+	Key:      1,
+	Value:    7, // countries in the drainage basin
+	Pattern:  reProperName,
+	Multi:    true,
+}, {
+	Name:     "mountains",
+	Dir:      "mountains",
+	Page:     "List_of_highest_mountains_on_Earth",
+	Question: "Which country contains **%s**?",
+	Key:      1,
+	Value:    11,
+	Pattern:  reProperName,
+	Multi:    true, // peaks on borders list each country
+}}
+
+// ListCard is a single code to country card.
+type ListCard struct {
+	Provenance
+
+	Key      string
+	Question string
+	Answer   string
+	Answers  []string // multiple answers, replacing Answer
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("list").Parse(`{{.Question}}
+<!--question-->
+{{if .Answers}}{{range .Answers}}- {{.}}
+{{end}}{{else}}**{{.Answer}}**{{end}}`))
+}
+
+func getListCards(spec ListSpec, refresh bool) ([]ListCard, error) {
+	page, err := getLockedWikiPage(wikipedia, spec.Page, refresh)
+	if err != nil {
+		return nil, err
+	}
+	var cards []ListCard
+	seen := make(map[string]bool)
+	for _, row := range parseWikiTables(page.Revisions[0].Text) {
+		if spec.Key >= len(row) || spec.Value >= len(row) {
+			continue
+		}
+		key, value := row[spec.Key], row[spec.Value]
+		if !spec.Pattern.MatchString(key) || value == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if x, ok := overrides.get(key, spec.Name); ok {
+			report.override(key, spec.Name)
+			value = x
+		}
+		card := ListCard{
+			Key:      key,
+			Question: fmt.Sprintf(spec.Question, key),
+			Answer:   value,
+		}
+		if spec.Multi {
+			card.Answers = splitList(value)
+		}
+		card.Article = pageURL(wikipedia, spec.Page)
+		card.Revision = page.Revisions[0].ID
+		cards = append(cards, card)
+		if spec.Limit != nil && len(cards) == *spec.Limit {
+			break
+		}
+	}
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("%s: no rows found in %s", spec.Name, spec.Page)
+	}
+	return cards, nil
+}
+
+// makeLists renders the named list decks, e.g. "ioc,fifa".
+func makeLists(names string) error {
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		var spec *ListSpec
+		for i := range listSpecs {
+			if listSpecs[i].Name == name {
+				spec = &listSpecs[i]
+			}
+		}
+		if spec == nil {
+			return fmt.Errorf("unknown list deck %q", name)
+		}
+
+		cards, err := getListCards(*spec, *flagRefreshAll)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join("countries", "codes", spec.Name)
+		if spec.Dir != "" {
+			dir = filepath.Join("countries", spec.Dir)
+		}
+		for _, card := range cards {
+			if err := makeTmpl(dir, card.Key, "list", &card); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestCleanWikiMarkup(t *testing.T) {
+	tests := []struct {
+		in       string
+		want     string
+		warnings int
+	}{
+		{"Trinidad &amp; Tobago", "Trinidad & Tobago", 0},
+		{"S&#227;o Tom&eacute;", "São Tomé", 0},
+		{"Map.svg<!-- old: Other.svg -->", "Map.svg", 0},
+		{"Map.svg<!--\nmultiline\n-->", "Map.svg", 0},
+		{`Map.svg<ref name="a"/>`, "Map.svg", 0},
+		{`Map.svg<ref name="a/b"/> | upright`, "Map.svg | upright", 0},
+		{`Map.svg<ref name="a/b" /> and more<ref>cite</ref>`, "Map.svg and more", 0},
+		{`Map.svg<ref name="x">{{cite web|url=https://a/b}}</ref> end`, "Map.svg end", 0},
+		{"Map.svg<br />", "Map.svg", 0},
+		{"Map.svg <span", "Map.svg ", 1},
+		{"Map.svg > Other.svg", "Map.svg ", 1},
+	}
+	for _, tt := range tests {
+		got, warnings := cleanWikiMarkup(tt.in)
+		if got != tt.want || len(warnings) != tt.warnings {
+			t.Errorf("cleanWikiMarkup(%q) = %q, %q, want %q with %d warnings", tt.in, got, warnings, tt.want, tt.warnings)
+		}
+	}
+}
+
+func TestCleanWikiText(t *testing.T) {
+	tests := map[string]string{
+		`[[Accra]]<ref name="cap/1"/> and [[Kumasi|Kumasi city]]`: "Accra and Kumasi city",
+		`{{lang|fr|Liberté}}<ref>a</ref>`:                         "Liberté",
+		"'''Bold''' and ''italic''":                               "Bold and italic",
+	}
+	for in, want := range tests {
+		if got := cleanWikiText(in); got != want {
+			t.Errorf("cleanWikiText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
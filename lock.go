@@ -0,0 +1,256 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/dustin/go-wikiparse"
+)
+
+const lockFile = "revisions.lock"
+
+// Lock pins the page and wikidata revisions and the file contents a build
+// used, so it can be rebuilt identically with --locked.
+type Lock struct {
+	mu sync.Mutex
+
+	Pages    map[string]LockedPage `json:"pages"`    // by requested name, other wikis prefixed by host
+	Entities map[string]LockedPage `json:"entities"` // wikidata item ID and revision by page name or ID
+	Queries  map[string]string     `json:"queries"`  // unversioned API responses, cache key to sha1
+	Files    map[string]string     `json:"files"`    // file name to sha1
+}
+
+// LockedPage is the revision of a page after following redirects, a
+// missing page has revision zero.
+type LockedPage struct {
+	Title    string `json:"title"`
+	Revision uint64 `json:"revision"`
+}
+
+var lock = Lock{
+	Pages:    make(map[string]LockedPage),
+	Entities: make(map[string]LockedPage),
+	Queries:  make(map[string]string),
+	Files:    make(map[string]string),
+}
+
+// read loads the lockfile, entries are merged with this run's so partial
+// runs keep the rest.
+func (l *Lock) read(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && !*flagLocked {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := json.Unmarshal(b, l); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (l *Lock) write(path string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(path, append(b, '\n'))
+}
+
+func (l *Lock) page(name string) (LockedPage, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, ok := l.Pages[name]
+	return p, ok
+}
+
+func (l *Lock) setPage(name string, p LockedPage) {
+	l.mu.Lock()
+	l.Pages[name] = p
+	l.mu.Unlock()
+}
+
+func (l *Lock) entity(key string) (LockedPage, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, ok := l.Entities[key]
+	return p, ok
+}
+
+func (l *Lock) setEntity(key string, p LockedPage) {
+	l.mu.Lock()
+	l.Entities[key] = p
+	l.mu.Unlock()
+}
+
+// file records the file's sha1, in locked mode it must match the lock.
+func (l *Lock) file(name string, b []byte) error {
+	return l.verify(l.Files, name, b)
+}
+
+// query records the API response's sha1, in locked mode it must match the
+// lock.
+func (l *Lock) query(key string, b []byte) error {
+	return l.verify(l.Queries, key, b)
+}
+
+func (l *Lock) verify(sums map[string]string, name string, b []byte) error {
+	h := sha1.Sum(b)
+	sum := hex.EncodeToString(h[:])
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if *flagLocked {
+		want, ok := sums[name]
+		if !ok {
+			return fmt.Errorf("%s: not in %s, rerun without --locked to add it", name, lockFile)
+		}
+		if want != sum {
+			return fmt.Errorf("%s: sha1 %s does not match locked %s, refetch with --refresh", name, sum, want)
+		}
+		return nil
+	}
+	sums[name] = sum
+	return nil
+}
+
+// getLockedPage returns the wikipedia page following redirects, recording
+// its revision under name. In locked mode the recorded revision is fetched.
+func getLockedPage(name, uname string, refresh bool) (*wikiparse.Page, error) {
+	if *flagLocked {
+		p, ok := lock.page(name)
+		if !ok {
+			return nil, fmt.Errorf("%s: not in %s", name, lockFile)
+		}
+		return getPageRevision(wikipedia, p.Title, p.Revision)
+	}
+
+	page, err := followRedirects(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	lock.setPage(name, LockedPage{Title: toURLName(page.Title), Revision: page.Revisions[0].ID})
+	return page, nil
+}
+
+// lockKey is the lock entry of a page, wikipedia pages by name and other
+// wikis prefixed by host.
+func lockKey(host, uname string) string {
+	if host == wikipedia {
+		return uname
+	}
+	return host + "/" + uname
+}
+
+// getLockedWikiPage returns the page of the wiki without following
+// redirects, recording its revision. In locked mode the recorded revision
+// is fetched. Missing pages return io.EOF like getWikiPage and are recorded
+// as missing.
+func getLockedWikiPage(host, uname string, refresh bool) (*wikiparse.Page, error) {
+	key := lockKey(host, uname)
+	if *flagLocked {
+		p, ok := lock.page(key)
+		if !ok {
+			return nil, fmt.Errorf("%s: not in %s", key, lockFile)
+		}
+		if p.Revision == 0 {
+			return nil, fmt.Errorf("%s: missing when locked: %w", key, io.EOF)
+		}
+		return getPageRevision(host, p.Title, p.Revision)
+	}
+
+	page, err := getWikiPage(host, uname, refresh)
+	if errors.Is(err, io.EOF) {
+		lock.setPage(key, LockedPage{Title: uname})
+	}
+	if err != nil {
+		return nil, err
+	}
+	lock.setPage(key, LockedPage{Title: toURLName(page.Title), Revision: page.Revisions[0].ID})
+	return page, nil
+}
+
+// queryLocked fetches an API response that can't be pinned to a revision,
+// e.g. a SPARQL query, recording its sha1. In locked mode the cached
+// response must match the lock.
+func queryLocked(key, url string, refresh bool) ([]byte, error) {
+	body, err := fetcher.Query(key, url, refresh)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock.query(key, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// getPageRevision fetches the revision of the page on the wiki by oldid.
+// Revisions never change so are always cached.
+func getPageRevision(host, uname string, revision uint64) (*wikiparse.Page, error) {
+	id := strconv.FormatUint(revision, 10)
+	fname := filepath.Join("pages", host, "oldid", id+".json")
+	params := url.Values{
+		"action":        {"query"},
+		"prop":          {"revisions"},
+		"revids":        {id},
+		"rvprop":        {"ids|content"},
+		"rvslots":       {"main"},
+		"format":        {"json"},
+		"formatversion": {"2"},
+		"maxlag":        {maxLag},
+	}
+	body, err := fetcher.Query(fname, "https://"+host+"/w/api.php?"+params.Encode(), false)
+	if err != nil {
+		return nil, err
+	}
+
+	var rsp struct {
+		Query struct {
+			Pages []struct {
+				PageID    uint64 `json:"pageid"`
+				Title     string `json:"title"`
+				Revisions []struct {
+					RevID uint64 `json:"revid"`
+					Slots struct {
+						Main struct {
+							Content string `json:"content"`
+						} `json:"main"`
+					} `json:"slots"`
+				} `json:"revisions"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	if err := json.Unmarshal(body, &rsp); err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	for _, p := range rsp.Query.Pages {
+		for _, r := range p.Revisions {
+			if r.RevID != revision {
+				continue
+			}
+			return &wikiparse.Page{
+				Title: p.Title,
+				ID:    p.PageID,
+				Revisions: []wikiparse.Revision{{
+					ID:   r.RevID,
+					Text: r.Slots.Main.Content,
+				}},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: revision %d of %s not found", fname, revision, uname)
+}
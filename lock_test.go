@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setLocked runs the test in --locked mode with a fresh lock.
+func setLocked(t *testing.T, locked bool) {
+	t.Helper()
+	pages, entities, queries, files, flag := lock.Pages, lock.Entities, lock.Queries, lock.Files, *flagLocked
+	lock.Pages = make(map[string]LockedPage)
+	lock.Entities = make(map[string]LockedPage)
+	lock.Queries = make(map[string]string)
+	lock.Files = make(map[string]string)
+	*flagLocked = locked
+	t.Cleanup(func() {
+		lock.Pages, lock.Entities, lock.Queries, lock.Files, *flagLocked = pages, entities, queries, files, flag
+	})
+}
+
+func TestLockEntity(t *testing.T) {
+	f := &memFetcher{Queries: map[string][]byte{
+		filepath.Join("pages", wikidata, "Ghana.json"): []byte(
+			`{"entities":{"Q117":{"id":"Q117","lastrevid":2000,"labels":{"en":{"language":"en","value":"Ghana"}}}}}`),
+		filepath.Join("pages", wikidata, "revision", "1000.json"): []byte(
+			`{"entities":{"Q117":{"id":"Q117","lastrevid":1000,"labels":{"en":{"language":"en","value":"Gold Coast"}}}}}`),
+	}}
+	withFetcher(t, f)
+
+	setLocked(t, false)
+	e, err := getEntity("Ghana", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lock.Entities["Ghana"]; got != (LockedPage{Title: "Q117", Revision: 2000}) {
+		t.Errorf("locked %+v", got)
+	}
+	if e.Label() != "Ghana" {
+		t.Errorf("label %q", e.Label())
+	}
+
+	// A locked build fetches the pinned revision, not the latest.
+	lock.Entities["Ghana"] = LockedPage{Title: "Q117", Revision: 1000}
+	*flagLocked = true
+	if e, err = getEntity("Ghana", false); err != nil {
+		t.Fatal(err)
+	}
+	if e.Label() != "Gold Coast" {
+		t.Errorf("locked label %q, want Gold Coast", e.Label())
+	}
+	if _, err := getEntity("Togo", false); err == nil || !strings.Contains(err.Error(), lockFile) {
+		t.Errorf("unlocked entity error %v", err)
+	}
+}
+
+func TestLockFile(t *testing.T) {
+	setLocked(t, false)
+	if err := lock.file("Flag_of_Ghana.svg", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	*flagLocked = true
+	if err := lock.file("Flag_of_Ghana.svg", []byte("a")); err != nil {
+		t.Error(err)
+	}
+	if err := lock.file("Flag_of_Ghana.svg", []byte("b")); err == nil {
+		t.Error("changed file accepted")
+	}
+	if err := lock.file("Flag_of_Togo.svg", []byte("a")); err == nil {
+		t.Error("file missing from the lock accepted")
+	}
+}
@@ -5,96 +5,443 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 	"time"
+	"unicode"
 
 	"github.com/dustin/go-wikiparse"
-	"golang.org/x/time/rate"
 )
 
 var (
-	flagCountry  = flag.String("country", "", "individual country to run")
-	flagPosition = flag.Int("position", 0, "position in list of countries")
+	flagCountry      = flag.String("country", "", "individual country to run")
+	flagPosition     = flag.Int("position", 0, "position in list of countries")
+	flagLimit        = flag.Int("limit", 0, "process at most N countries, 0 for all")
+	flagOnly         = flag.String("only", "", "process countries matching the regular expression")
+	flagDifficulty   = flag.String("difficulty", "", "split decks by difficulty tier as tags or sub-deck dirs: tags or dirs")
+	flagOrder        = flag.Bool("order", false, "write a suggested study order.json per deck")
+	flagTags         = flag.String("tags", "", "comma separated tags, only generate countries with any of them, e.g. europe,microstate,landlocked")
+	flagRefresh      = flag.String("refresh", "", "comma separated countries to refetch, bypassing caches")
+	flagRefreshAll   = flag.Bool("refresh-all", false, "refetch all pages and files, bypassing caches")
+	flagAudio        = flag.Bool("audio", false, "generate the pronunciation audio deck")
+	flagIncludeIPA   = flag.Bool("include-ipa", false, "include IPA pronunciation in name answers")
+	flagNative       = flag.Bool("native-capitals", false, "include native script names on capital answers")
+	flagAltAnswers   = flag.Bool("alt-answers", false, "include alternative answers from wikidata in card front matter")
+	flagSymbols      = flag.Bool("symbols", false, "generate the national symbols deck")
+	flagHeritage     = flag.Bool("heritage", false, "generate the UNESCO World Heritage sites deck")
+	flagHighest      = flag.Bool("highest", false, "generate the highest point deck")
+	flagLandlocked   = flag.Bool("landlocked", false, "tag landlocked and island countries and generate their decks")
+	flagSuperlatives = flag.Bool("superlatives", false, "generate the ranked superlatives deck")
+	flagCurrencies   = flag.Bool("currencies", false, "generate the currency to countries deck")
+	flagTimeZones    = flag.Bool("timezones", false, "generate the time zone deck")
+	flagDriving      = flag.Bool("driving", false, "generate the driving side deck")
+	flagIndependence = flag.Bool("independence", false, "generate the independence and national day decks")
+	flagDateFormat   = flag.String("date-format", "full", "date answer format: full or year, national days have no year so are always in full")
+	flagMotto        = flag.Bool("motto", false, "generate the national motto decks")
+	flagWaters       = flag.Bool("waters", false, "generate the ocean and sea access deck")
+	flagBorders      = flag.Bool("borders", false, "generate the border count deck")
+	flagDistances    = flag.Bool("distances", false, "generate the capital distance comparison deck")
+	flagProfile      = flag.Bool("profile", false, "generate the country profile summary deck")
+	flagDialect      = flag.String("dialect", "markdown", "card format: markdown, dashes, obsidian or anki")
+	flagNaming       = flag.String("naming", namingWiki, "card file naming policy: wiki or slug")
+	flagCheck        = flag.Bool("check", false, "fail if regenerating would change any output, without writing")
+	flagLayout       = flag.String("layout", layoutFlat, "output layout: flat, continent or country")
+	flagRemoteImages = flag.Bool("remote-images", false, "reference images on upload.wikimedia.org instead of downloading them")
+	flagImageWidth   = flag.Int("image-width", 640, "width of remote images in pixels")
+	flagPadFlags     = flag.String("pad-flags", "", "letterbox flag images to an aspect ratio, e.g. 3:2")
+	flagZoomMaps     = flag.Bool("zoom-maps", false, "add zoomed maps for microstates and small islands")
+	flagAltText      = flag.String("alt-text", altTemplate, "image alt text source: template or commons")
+	flagAcceptList   = flag.Bool("accept-list-changes", false, "update countries.txt when the scraped country list differs from it")
+	flagExpect       = flag.Int("expect", 193, "expected number of countries in the list, 0 to disable")
+	flagAllowAnomaly = flag.Bool("allow-anomalies", false, "generate countries with implausible values instead of failing, still recording them in the report")
+	flagFetchJobs    = flag.Int("fetch-jobs", 4, "concurrent country page fetches")
+	flagParseJobs    = flag.Int("parse-jobs", 4, "concurrent country extractions")
+	flagRenderJobs   = flag.Int("render-jobs", 2, "concurrent country renders")
+	flagKeepGoing    = flag.Bool("keep-going", false, "continue past country failures, recording them in the report")
+	flagLocked       = flag.Bool("locked", false, "fetch the page and wikidata revisions and verify the files and queries pinned in revisions.lock")
+	flagReport       = flag.String("report", "report.json", "path of the run report, empty to disable")
+	flagProxy        = flag.String("proxy", "", "proxy URL for requests, defaults to HTTPS_PROXY from the environment")
+	flagPprof        = flag.String("pprof", "", "serve profiling endpoints at the address, e.g. localhost:6060")
+	flagMetrics      = flag.Bool("metrics", false, "print request and stage duration percentiles after the run")
+	flagRates        = flag.String("rates", "", "comma separated requests per second per host, e.g. upload.wikimedia.org=10")
+	flagTimeout      = flag.Duration("timeout", 60*time.Second, "timeout connecting and waiting for the response of each request, bodies are streamed without one")
+	flagLists        = flag.String("lists", "", "comma separated list decks to generate: ioc, fifa, vehicle, cities, rivers, mountains")
+	flagTopCities    = flag.Int("top-cities", 100, "number of the largest cities in the cities list deck")
+	flagQuizletSplit = flag.Bool("quizlet-split", false, "split quizlet exports into sets of at most 2,000 cards")
+	flagDeckHook     = flag.String("deck-hook", "", "shell command run by sync to import the decks, DECK_ROOT is set to the decks directory")
+	flagGenGo        = flag.String("gen-go", "", "directory to generate the countriesdata Go package in, e.g. countriesdata")
+	flagRelease      = flag.Bool("release", false, "bump VERSION and add a CHANGELOG.md entry for changed countries, capitals and flags")
+	flagMemberships  = flag.String("memberships", "", "comma separated membership decks to generate: eu, schengen, eurozone, commonwealth, francophonie")
+	flagEconomy      = flag.Bool("economy", false, "generate the GDP, income group and industries decks, answers are stamped with the data year as they date quickly")
+	flagHDI          = flag.Bool("hdi", false, "generate the Human Development Index tier deck")
+	flagSensitive    = flag.Bool("sensitive", false, "generate the religion and ethnic group decks, tagged sensitive and excluded by default")
+	flagUnits        = flag.String("units", unitsMetric, "area and distance answer units: metric, imperial or both")
+	flagNumberStyle  = flag.String("number-style", numberEN, "numeric answer style: en, de, fr, ch or short")
+	flagHistorical   = flag.Bool("historical-flags", false, "generate the former flags deck from historical_flags.txt")
+	flagCapitalMoves = flag.Bool("capital-moves", false, "generate the former capital cards from capital_moves.txt")
+	flagSubregions   = flag.Bool("subregions", false, "generate the UN subregion deck and tag countries with their subregion")
+	flagFlagFeature  = flag.Bool("flag-features", false, "generate the flag feature cards from flag_features.txt")
+	flagIndex        = flag.Bool("index", false, "write an index.md per deck tabling its cards")
+	flagStats        = flag.Bool("stats", true, "write stats.md and stats.json summarising the decks, overrides and fallbacks")
+	flagUploadJobs   = flag.Int("upload-jobs", 4, "concurrent uploads of the upload command")
+	flagMediaStore   = flag.String("media-store", "", "copy media to media/ named by content hash and reference it from cards at the base URL, e.g. https://example.org/media/")
+	flagAddr         = flag.String("addr", "localhost:8080", "address the serve command listens on")
+	flagPostRender   = flag.String("post-render", "", "shell command transforming each card and media file from stdin to stdout before it is written, POST_RENDER_KIND and POST_RENDER_PATH are set")
+	flagLargestCity  = flag.Bool("largest-city", false, "generate the is the capital the largest city deck")
+	flagGovernment   = flag.Bool("government", false, "generate the form of government deck and the monarchy lists")
+	flagCurrencySym  = flag.Bool("currency-symbols", false, "generate the currency symbol deck from currency_symbols.txt")
+	flagCorrections  = flag.String("apply-corrections", "", "csv of country,field,value,reason corrections applied after extraction, e.g. corrections.csv")
+	flagDecks        = flag.String("decks", "", "comma separated card types to generate, ignoring their flags, e.g. flags,capitals")
+	flagLocMerge     = flag.String("location-merge", mergeReplaceImages, "how location answers keep their hand written images: keep-manual, replace-images or append")
+	flagOpenFailures = flag.Bool("open-failures", false, "print the files to fix after the run as file:line and open them in $EDITOR if set")
+	flagEmojiClues   = flag.Bool("emoji-clues", false, "generate the emoji clue deck from emoji_clues.txt")
+	flagMapPatterns  = flag.String("map-patterns", defaultMapPatterns, "comma separated commons map name patterns in order of preference")
+	flagFlagVariant  = flag.String("flag-variant", flagVariantCivil, "flag preferred over infobox variants: civil for Flag_of_X.svg or state")
+	flagArticle      = flag.String("article", articleKeep, "leading \"The\" of country names, e.g. The Gambia: keep or strip")
+	flagNameStyle    = flag.String("name-style", nameStyleCommon, "country names asked: common or official, the other is an alternative answer")
+	flagJSON         = flag.Bool("json", false, "write countries.json of the extracted data, see schema/countries.schema.json")
+	flagAudit        = flag.Bool("audit-overrides", false, "re-extract overridden fields and report which overrides are still required, without generating")
+	flagPopBuckets   = flag.String("pop-buckets", "", "generate the population bucket deck with the comma separated options, e.g. 5m,50m,500m")
 )
 
 var (
 	// {{Flagicon|Country}} [[Actual Country|Country]]
-	reCountry = regexp.MustCompile(`{{Flagicon\|[\w ]+}} \[\[(.+?)[\||\]\]]`)
+	reCountry = regexp.MustCompile(`{{[Ff]lagicon\|[^}]+}} \[\[(.+?)[|\]]`)
 
-	// image_map = Country.svg\n
-	reImageMap  = regexp.MustCompile(`image_map\s+= (.+?)\n`)
-	reImageMap2 = regexp.MustCompile(`image_map2\s+= (.+?)\n`)
+	// image_map = Country.svg\n, image_map2 = ...
+	reImageMap = regexp.MustCompile(`image_map\d*\s+= (.+?)\n`)
 
 	// image_flag = Country.svg\n
 	reImageFlag = regexp.MustCompile(`image_flag\s+= (.+?)\n`)
 
 	// capital = Capital\n
 	reCapital = regexp.MustCompile(`capital\s+= (.+?)\n`)
-)
 
-var limit = rate.NewLimiter(rate.Every(time.Second), 2)
+	// {{IPAc-en|ˈ|f|r|ɑː|n|s}}
+	reIPA = regexp.MustCompile(`{{IPAc-en\|(.+?)}}`)
+
+	// {{audio|en|En-us-Country.ogg|Audio (US)}}
+	reAudio = regexp.MustCompile(`{{audio\|en\|([^|}]+)`)
+)
 
 type Country struct {
-	Name           string
-	MapImageURL    string // image url
-	FlagImageURL   string
-	Capital        string
-	AnswerLocation string // location answer, data from card.
+	Provenance
+
+	Name            string
+	Title           string // canonical wikipedia title, after redirects.
+	UName           string // wikipedia page name, after redirects.
+	MapName         string // commons file names
+	FlagName        string
+	ZoomMapName     string
+	AudioName       string
+	MapImageURL     string // image url
+	ZoomMapImageURL string // zoomed map for small countries, may be empty.
+	MapAlt          string
+	MapAltHidden    string // alt text without the country name.
+	FlagAlt         string
+	FlagAltHidden   string
+	FlagImageURL    string
+	Capital         string
+	CapitalNative   string // native script capital, empty if latin.
+	AnswerLocation  string // location answer, data from card.
+	AudioURL        string // pronunciation audio, empty if unavailable.
+	IPA             string // english pronunciation, empty if unavailable.
+
+	HighestPoint     string
+	HighestElevation float64 // metres
+
+	Population       float64
+	PopulationBucket string  // closest --pop-buckets option, e.g. 50 million
+	Area             float64 // km²
+	Coastline        float64 // km
+	Neighbors        []string
+	Currencies       []string
+	Languages        []string
+
+	CapitalLocation *LatLon  // may be nil
+	Waters          []string // bordering oceans and seas
+
+	TimeZones []string
+	UTCOffset string
+	DrivesOn  string // left or right
+
+	Independence string // date of independence, formatted per --date-format.
+	NationalDay  string
+
+	Motto            string
+	MottoTranslation string
+
+	GDP              float64 // US dollars
+	GDPYear          int
+	GDPPerCapita     float64
+	GDPPerCapitaYear int
+	IncomeGroup      string // low, lower-middle, upper-middle or high
+	Industries       []string
+
+	HDI     float64 // human development index, zero if unknown
+	HDITier string  // Very high, High, Medium or Low
+
+	Religions    []Share // largest first, as listed in the infobox
+	EthnicGroups []Share
+
+	CapitalMove *CapitalMove // former capital, nil if never moved.
+	EmojiClue   *EmojiClue   // curated rebus, nil if none.
+
+	Symbols  []Symbol  // national animal, bird and flower
+	Heritage *Heritage // world heritage sites, nil if not extracted
+
+	Government string // infobox government type, e.g. Unitary parliamentary republic
+	Monarchy   bool
+
+	LargestCity           string // same as Capital if the capital is the largest city.
+	LargestCityPopulation float64
+	CapitalPopulation     float64
+
+	Continents []string
+	Subregion  string // UN geoscheme subregion, e.g. Central Asia
+	Landlocked bool
+	Island     bool
+	Tags       []string
+	Difficulty string // beginner, intermediate or advanced
+
+	AltNames        []string // accepted alternatives for Name.
+	CapitalAltNames []string // accepted alternatives for Capital.
+
+	Warnings []Warning // malformed source markup.
+}
+
+// FrontMatter is the card metadata rendered before the question.
+type FrontMatter struct {
+	AltAnswers []string
+	Tags       []string
 }
 
-var tmpls *template.Template
+var tmpls = template.New("cards").Funcs(template.FuncMap{
+	"front": func(alt, tags []string) FrontMatter {
+		return FrontMatter{AltAnswers: alt, Tags: tags}
+	},
+	"inc":  func(i int) int { return i + 1 },
+	"sub":  func(a, b int) int { return a - b },
+	"int":  formatNumber,
+	"lat":  formatLat,
+	"usd":  formatMoney,
+	"area": formatArea,
+})
 
 func init() {
-	tmpls = template.Must(template.New("location").Parse(`Where in the world is **{{.Name}}**?
+	tmpls = template.Must(tmpls.New("front-matter").Parse(`{{if or .AltAnswers .Tags}}---
+{{if .AltAnswers}}alt-answers:
+{{range .AltAnswers}}  - {{printf "%q" .}}
+{{end}}{{end}}{{if .Tags}}tags: [{{range $i, $v := .Tags}}{{if $i}}, {{end}}{{$v}}{{end}}]
+{{end}}---
+{{end}}`))
+	tmpls = template.Must(tmpls.New("location").Funcs(template.FuncMap{
+		"maps": func(c *Country) []string { return mergeMaps(*flagLocMerge, c) },
+	}).Parse(`{{template "front-matter" front nil .Tags}}Where in the world is **{{.Name}}**?
 <!--question-->
-{{.AnswerLocation}}
+{{.AnswerLocation}}{{range maps .}}
+
+{{.}}{{end}}`))
+	tmpls = template.Must(tmpls.New("map").Parse(`{{template "front-matter" front .AltNames .Tags}}Which country is this?
 
-![Map of {{.Name}}]({{.MapImageURL}})`))
-	tmpls = template.Must(tmpls.New("world").Parse(`Which country is this?
+![{{.MapAltHidden}}]({{.MapImageURL}}){{if .ZoomMapImageURL}}
 
-![Map of a country]({{.MapImageURL}})
+![Zoomed map of a country]({{.ZoomMapImageURL}}){{end}}
 <!--question-->
-**{{.Name}}**`))
-	tmpls = template.Must(tmpls.New("capital").Parse(`What is the capital of **{{.Name}}**?
+**{{.Name}}**{{if .IPA}}
+
+/{{.IPA}}/{{end}}`))
+	tmpls = template.Must(tmpls.New("capital").Parse(`{{template "front-matter" front .CapitalAltNames .Tags}}What is the capital of **{{.Name}}**?
+<!--question-->
+{{.Capital}}{{if .CapitalNative}} *({{.CapitalNative}})*{{end}}`))
+	tmpls = template.Must(tmpls.New("highest").Parse(`What is the highest point of **{{.Name}}**?
+<!--question-->
+{{.HighestPoint}}{{if .HighestElevation}} *({{printf "%.0f" .HighestElevation}} m)*{{end}}`))
+	tmpls = template.Must(tmpls.New("landlocked").Parse(`{{template "front-matter" front nil .Tags}}Is **{{.Name}}** landlocked?
+<!--question-->
+{{if .Landlocked}}Yes{{else}}No{{end}}`))
+	tmpls = template.Must(tmpls.New("timezone").Parse(`{{template "front-matter" front nil .Tags}}What time zone{{if gt (len .TimeZones) 1}}s do{{else}} does{{end}} **{{.Name}}** use?
+<!--question-->
+{{range $i, $v := .TimeZones}}{{if lt $i 6}}- {{$v}}
+{{end}}{{end}}{{if gt (len .TimeZones) 6}}- *and {{sub (len .TimeZones) 6}} more*
+{{end}}{{if .UTCOffset}}
+*UTC{{.UTCOffset}}*{{end}}`))
+	tmpls = template.Must(tmpls.New("borders").Parse(`{{template "front-matter" front nil .Tags}}How many countries border **{{.Name}}**?
+<!--question-->
+**{{len .Neighbors}}**
+
+{{range $i, $v := .Neighbors}}{{if $i}}, {{end}}{{$v}}{{end}}`))
+	tmpls = template.Must(tmpls.New("waters").Parse(`{{template "front-matter" front nil .Tags}}Which oceans and seas does **{{.Name}}** border?
+<!--question-->
+{{range .Waters}}- {{.}}
+{{end}}`))
+	tmpls = template.Must(tmpls.New("driving").Parse(`{{template "front-matter" front nil .Tags}}Which side of the road does **{{.Name}}** drive on?
+<!--question-->
+{{if eq .DrivesOn "left"}}Left{{else}}Right{{end}}`))
+	tmpls = template.Must(tmpls.New("independence").Parse(`{{template "front-matter" front nil .Tags}}When did **{{.Name}}** gain independence?
+<!--question-->
+{{.Independence}}`))
+	tmpls = template.Must(tmpls.New("national-day").Parse(`{{template "front-matter" front nil .Tags}}What is the national day of **{{.Name}}**?
+<!--question-->
+{{.NationalDay}}`))
+	tmpls = template.Must(tmpls.New("motto").Parse(`{{template "front-matter" front nil .Tags}}What is the national motto of **{{.Name}}**?
+<!--question-->
+*{{.Motto}}*{{if .MottoTranslation}}
+
+{{.MottoTranslation}}{{end}}`))
+	tmpls = template.Must(tmpls.New("motto-reverse").Parse(`{{template "front-matter" front .AltNames .Tags}}Which country's motto is *{{.Motto}}*{{if .MottoTranslation}} ({{.MottoTranslation}}){{end}}?
 <!--question-->
-{{.Capital}}`))
-	tmpls = template.Must(tmpls.New("flag").Parse(`Which country does this flag belong to?
+**{{.Name}}**`))
+	tmpls = template.Must(tmpls.New("pronunciation").Parse(`{{template "front-matter" front .AltNames .Tags}}Which country is this?
 
-![Flag of {{.Name}}]({{.FlagImageURL}})
+<audio controls src="{{.AudioURL}}"></audio>
 <!--question-->
 **{{.Name}}**`))
+	tmpls = template.Must(tmpls.New("flag").Parse(`{{template "front-matter" front .AltNames .Tags}}Which country does this flag belong to?
+
+![{{.FlagAltHidden}}]({{.FlagImageURL}})
+<!--question-->
+**{{.Name}}**{{if .IPA}}
+
+/{{.IPA}}/{{end}}`))
 }
 
-func get(url string) ([]byte, error) {
-	if err := limit.Wait(context.Background()); err != nil {
+// Requests are retried with backoff while the servers are lagged or
+// throttling.
+const (
+	maxRetries   = 5
+	retryBackoff = 2 * time.Second
+	maxLag       = "5" // seconds of replication lag tolerated by API requests
+)
+
+// open requests the url returning the response body for streaming, the
+// downloaded bytes are reported on close.
+func open(url string) (io.ReadCloser, error) {
+	rsp, err := openRange(url, 0, "")
+	if err != nil {
 		return nil, err
 	}
+	return rsp.Body, nil
+}
+
+// errRangeNotSatisfiable is returned when the range starts at or after the
+// end of the file.
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
 
-	rsp, err := http.Get(url)
+// openRange requests the url from the byte offset if the file still matches
+// the ifRange validator, else the whole body is returned with status 200.
+// The body is read by the caller.
+func openRange(url string, offset int64, ifRange string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := limiter(url).Wait(context.Background()); err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+			if ifRange != "" {
+				req.Header.Set("If-Range", ifRange)
+			}
+		}
+		start := time.Now()
+		rsp, err := client.Do(req)
+		metrics.since("request", start)
+		if err != nil {
+			return nil, err
+		}
+		lagged := rsp.Header.Get("MediaWiki-API-Error") == "maxlag"
+		if (rsp.StatusCode == 200 || rsp.StatusCode == http.StatusPartialContent) && !lagged {
+			rsp.Body = &countingReader{r: rsp.Body}
+			return rsp, nil
+		}
+		rsp.Body.Close()
+		if rsp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			return nil, fmt.Errorf("%w %s", errRangeNotSatisfiable, url)
+		}
+
+		retry := lagged || rsp.StatusCode == http.StatusTooManyRequests ||
+			rsp.StatusCode == http.StatusServiceUnavailable
+		if !retry || attempt == maxRetries {
+			if lagged {
+				return nil, fmt.Errorf("maxlag exceeded %s", url)
+			}
+			return nil, fmt.Errorf("%s %s", rsp.Status, url)
+		}
+		wait := retryBackoff << uint(attempt)
+		if secs, err := strconv.Atoi(rsp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			wait = time.Duration(secs) * time.Second
+		}
+		report.warnf(url, "%s, retrying in %v", rsp.Status, wait)
+		time.Sleep(wait)
+	}
+}
+
+// validator returns the response's strong ETag, else its Last-Modified time,
+// for resuming with If-Range. Empty if neither is usable.
+func validator(h http.Header) string {
+	if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return h.Get("Last-Modified")
+}
+
+// countingReader reports the bytes read as downloaded.
+type countingReader struct {
+	r io.ReadCloser
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	report.download(c.n)
+	return c.r.Close()
+}
+
+func get(url string) ([]byte, error) {
+	r, err := open(url)
 	if err != nil {
 		return nil, err
 	}
-	defer rsp.Body.Close()
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
 
-	if rsp.StatusCode != 200 {
-		return nil, fmt.Errorf("%s %s", rsp.Status, url)
+func head(url string) error {
+	if err := limiter(url).Wait(context.Background()); err != nil {
+		return err
 	}
 
-	body, err := ioutil.ReadAll(rsp.Body)
+	rsp, err := client.Head(url)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return body, nil
+	rsp.Body.Close()
+
+	if rsp.StatusCode != 200 {
+		return fmt.Errorf("%s %s", rsp.Status, url)
+	}
+	return nil
 }
 
 func writeFile(r io.Reader, path string) error {
@@ -107,31 +454,43 @@ func writeFile(r io.Reader, path string) error {
 	return err
 }
 
-func getPage(uname string) (io.Reader, error) {
-	fname := "pages/" + uname + ".txt"
-	if f, err := os.Open(fname); err == nil {
-		defer f.Close()
-
-		body, err := ioutil.ReadAll(f)
-		if err != nil {
-			return nil, err
+// isRefresh reports whether the caches should be bypassed for the country.
+func isRefresh(name string) bool {
+	if *flagRefreshAll {
+		return true
+	}
+	uname := toURLName(name)
+	for _, v := range strings.Split(*flagRefresh, ",") {
+		if v = strings.TrimSpace(v); v != "" && toURLName(v) == uname {
+			return true
 		}
-		return bytes.NewReader(body), nil
 	}
+	return false
+}
 
-	url := "https://en.wikipedia.org/wiki/Special:Export/" + uname
-	body, err := get(url)
-	if err != nil {
-		return nil, err
+// Wiki hosts that pages are exported from.
+const (
+	wikipedia  = "en.wikipedia.org"
+	wiktionary = "en.wiktionary.org"
+)
+
+func pagePath(host, uname string) string {
+	if host == wikipedia {
+		return "pages/" + uname + ".txt"
 	}
-	return bytes.NewReader(body), ioutil.WriteFile(fname, body, 0776)
+	return "pages/" + host + "/" + uname + ".txt"
+}
+
+func getPage(host, uname string, refresh bool) (io.ReadCloser, error) {
+	return fetcher.Page(host, uname, refresh)
 }
 
-func getWikiPage(uname string) (*wikiparse.Page, error) {
-	f, err := getPage(uname)
+func getWikiPage(host, uname string, refresh bool) (*wikiparse.Page, error) {
+	f, err := getPage(host, uname, refresh)
 	if err != nil {
 		return nil, fmt.Errorf("get page error: %w", err)
 	}
+	defer f.Close()
 	p, err := wikiparse.NewParser(f)
 	if err != nil {
 		return nil, fmt.Errorf("parser error: %w", err)
@@ -143,8 +502,15 @@ func getWikiPage(uname string) (*wikiparse.Page, error) {
 	return page, nil
 }
 
+// toURLName returns the unescaped page or file name used for cache keys and
+// output paths, percent-encoded names are decoded and accents composed.
 func toURLName(name string) string {
-	return strings.Replace(name, " ", "_", -1)
+	if strings.Contains(name, "%") {
+		if s, err := url.PathUnescape(name); err == nil {
+			name = s
+		}
+	}
+	return composeName(strings.Replace(name, " ", "_", -1))
 }
 
 func wikiFileURL(name string) string {
@@ -152,109 +518,248 @@ func wikiFileURL(name string) string {
 	m := md5.New()
 	m.Write([]byte(uname))
 	h := hex.EncodeToString(m.Sum(nil))
-	// TODO: should path be escaped?
-	return "https://upload.wikimedia.org/wikipedia/commons/" + string(h[0]) + "/" + h[0:2] + "/" + uname
+	return "https://upload.wikimedia.org/wikipedia/commons/" + string(h[0]) + "/" + h[0:2] + "/" + escapeURLName(uname)
 }
 
-func getFile(uname string) (io.Reader, error) {
-	//fmt.Println("url", wikiFileURL(uname))
-	fname := "files/" + uname
-	if f, err := os.Open(fname); err == nil {
-		defer f.Close()
-
-		body, err := ioutil.ReadAll(f)
-		if err != nil {
-			return nil, err
-		}
-		return bytes.NewReader(body), nil
-	}
-
-	url := wikiFileURL(uname)
-	body, err := get(url)
-	if err != nil {
-		return nil, err
-	}
-	return bytes.NewReader(body), ioutil.WriteFile(fname, body, 0666)
+func getFile(uname string, refresh bool) (io.ReadCloser, error) {
+	return fetcher.File(uname, refresh)
 }
 
-func makeFile(dir, name string) error {
-	r, err := getFile(name)
+func makeFile(dir, name string, refresh bool) error {
+	r, err := getFile(name, refresh)
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
 		return err
 	}
-	f, err := os.Create(dir + "/" + name)
-	if err != nil {
+	if err := lock.file(name, b); err != nil {
 		return err
 	}
-	defer f.Close()
-	_, err = io.Copy(f, r)
-	return err
+	return writeMedia(filepath.Join(dir, setLocalName(name, b)), b)
+}
+
+// wikiThumbURL returns the url of a scaled rendering of the file, svg files
+// are rendered to png.
+func wikiThumbURL(name string, width int) string {
+	uname := toURLName(name)
+	h := md5.Sum([]byte(uname))
+	hs := hex.EncodeToString(h[:])
+	thumb := strconv.Itoa(width) + "px-" + uname
+	if strings.HasSuffix(strings.ToLower(uname), ".svg") {
+		thumb += ".png"
+	}
+	return "https://upload.wikimedia.org/wikipedia/commons/thumb/" + string(hs[0]) + "/" + hs[0:2] + "/" + escapeURLName(uname) + "/" + escapeURLName(thumb)
+}
+
+// makeImage downloads the image to the images directory, in remote image
+// mode nothing is downloaded.
+func makeImage(dir, name string, refresh bool) error {
+	if *flagRemoteImages {
+		return nil
+	}
+	return makeFile(filepath.Join(dir, "images"), name, refresh)
+}
+
+// imageURL returns the card reference to an image stored by makeImage.
+func imageURL(name string) string {
+	if *flagRemoteImages {
+		return wikiThumbURL(name, *flagImageWidth)
+	}
+	return "images/" + localName(name)
 }
 
 func makeTmpl(dir, name, tmpl string, data interface{}) error {
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	var buf bytes.Buffer
+	if err := tmpls.ExecuteTemplate(&buf, tmpl, data); err != nil {
 		return err
 	}
-	path := filepath.Join(dir, name+".md")
-	f, err := os.Create(path)
+	card, err := applyDialect(contentAddress(dir, buf.String()))
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	return tmpls.ExecuteTemplate(f, tmpl, data)
+	buf.Reset()
+	buf.WriteString(card)
+	if err := writeProvenance(&buf, data); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, cardName(name)+".md")
+	b, err := postRender("card", path, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return writeOutput(path, b)
 }
 
 func readAnswer(dir, name string) (string, error) {
-	path := filepath.Join(dir, name+".md")
+	path := filepath.Join(dir, cardName(name)+".md")
 	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// Fallback to the wiki name before a naming policy change.
+		path = filepath.Join(dir, name+".md")
+		b, err = ioutil.ReadFile(path)
+	}
 	if err != nil {
 		return "", err
 	}
-	ss := strings.Split(string(b), "<!--question-->")
-	if len(ss) != 2 {
+	_, ans, ok := splitCard(string(b))
+	if !ok {
 		return "", fmt.Errorf("missing %s answer", path)
 	}
-	return strings.TrimSpace(ss[1]), nil
+	if i := strings.Index(ans, provenanceTag); i > -1 {
+		ans = ans[:i]
+	}
+	return strings.TrimSpace(ans), nil
+}
+
+// Warning is a problem with the source markup that doesn't stop the country
+// being generated.
+type Warning struct {
+	Field   string // e.g. map, flag
+	Message string
 }
 
-// Try to parse a file link (there could be multiple).
-func parseWikiFile(s string) string {
-	//a := s
+func (w Warning) String() string { return w.Field + ": " + w.Message }
+
+// Try to parse a file link (there could be multiple). Malformed markup is
+// returned as warnings.
+func parseWikiFile(s string) (string, []string) {
 	const (
 		fileTag     = "File:"
 		itemFileTag = "[[File:"
 	)
+	s, warnings := cleanWikiMarkup(s)
 	if i := strings.Index(s, itemFileTag); i > -1 {
 		s = s[i:]
 		s = strings.TrimPrefix(s, itemFileTag)
-		s = s[:strings.Index(s, "|")]
+		if j := strings.IndexAny(s, "|]"); j > -1 {
+			s = s[:j]
+		} else {
+			warnings = append(warnings, "unterminated file link")
+		}
 	} else if i := strings.Index(s, fileTag); i > -1 {
 		s = s[i:]
 		s = strings.TrimPrefix(s, fileTag) // At EOF
 	}
 
-	// Trim &lt->&gt comments.
-	if i := strings.Index(s, "<"); i > -1 {
-		j := strings.Index(s, ">")
-		if j < i {
-			panic(fmt.Sprintf("%s %v %v %s", s, i, j, "</>"))
-		}
-		s = s[:i] + s[j+1:]
-	}
-
-	// Trim {{!}} comments.
+	// Trim {{!}} escaped pipes and anything after.
 	if i := strings.Index(s, "{{!}}"); i > -1 {
 		s = s[:i]
 	}
 
 	s = strings.TrimSpace(s)
 	s = toURLName(s)
-	//fmt.Println(a, "->", s)
-	return s
+	return s, warnings
+}
+
+// Try to parse the first english IPA transcription, labels and named
+// parameters are dropped.
+func parseIPA(text string) string {
+	v := reIPA.FindStringSubmatch(text)
+	if len(v) != 2 {
+		return ""
+	}
+	var b strings.Builder
+	for _, p := range strings.Split(v[1], "|") {
+		if strings.Contains(p, "=") {
+			continue
+		}
+		switch p {
+		case "lang", "local", "pron", "US", "UK", "us", "uk":
+			continue
+		}
+		b.WriteString(strings.TrimSpace(p))
+	}
+	return b.String()
+}
+
+// Try to find an English pronunciation file on the wiktionary entry.
+func getAudioName(name string, refresh bool) (string, error) {
+	page, err := getLockedWikiPage(wiktionary, toURLName(name), refresh)
+	if errors.Is(err, io.EOF) {
+		return "", nil // No entry.
+	}
+	if err != nil {
+		return "", err
+	}
+	v := reAudio.FindStringSubmatch(page.Revisions[0].Text)
+	if len(v) != 2 {
+		return "", nil
+	}
+	return toURLName(strings.TrimSpace(v[1])), nil
+}
+
+func isLatin(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// getCapitalEntity returns the wikidata item of the capital, nil if the
+// country has multiple capitals.
+func getCapitalEntity(uname string, refresh bool) (*Entity, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	ids := e.ItemIDs("P36") // capital
+	if len(ids) != 1 {
+		return nil, nil
+	}
+	return getEntityByID(ids[0], refresh)
+}
+
+// Try to find the native name of the capital from wikidata, only non latin
+// script names are returned.
+func getNativeCapital(uname string, refresh bool) (string, error) {
+	capital, err := getCapitalEntity(uname, refresh)
+	if err != nil || capital == nil {
+		return "", err
+	}
+	for _, t := range capital.Texts("P1705") { // native label
+		if !isLatin(t.Value) {
+			return t.Value, nil
+		}
+	}
+	return "", nil
+}
+
+// altNames returns the english label and aliases of the entity excluding
+// name, codes and emoji.
+func altNames(e *Entity, name string) []string {
+	seen := map[string]bool{strings.ToLower(name): true}
+	var names []string
+	add := func(s string) {
+		k := strings.ToLower(s)
+		if seen[k] || strings.IndexFunc(s, unicode.IsLetter) == -1 || isCode(s) {
+			return
+		}
+		seen[k] = true
+		names = append(names, s)
+	}
+	add(e.Label())
+	for _, t := range e.Aliases["en"] {
+		add(t.Value)
+	}
+	return names
+}
+
+// isCode reports whether s is an ISO style code or abbreviation of two or
+// three capital letters, e.g. CZ, CZE, USA or ČR.
+func isCode(s string) bool {
+	n := 0
+	for _, r := range s {
+		if !unicode.IsUpper(r) {
+			return false
+		}
+		n++
+	}
+	return n == 2 || n == 3
 }
 
 // Try to parse the link.
@@ -272,12 +777,379 @@ func parseWikiLink(s string) string {
 	return s
 }
 
+var (
+	// Start of the next infobox field or the end of the infobox.
+	reFieldEnd = regexp.MustCompile(`\n\s*(\||}})`)
+
+	// reBreak splits multi-line infobox values.
+	reBreak = regexp.MustCompile(`(?i)<br\s*/?>|\n`)
+
+	// {{ubl|A|B}}, {{plainlist|\n* A\n* B}}
+	reListTemplate = regexp.MustCompile(`(?i){{\s*(ubl|ublist|unbulleted list|plainlist|plain list|flatlist|hlist)\s*\|`)
+)
+
+// infoboxField returns the raw wikitext value of the infobox field, which may
+// span multiple lines.
+func infoboxField(text, name string) string {
+	re := regexp.MustCompile(`(?m)^\s*\|\s*` + regexp.QuoteMeta(name) + `\s*=`)
+	loc := re.FindStringIndex(text)
+	if loc == nil {
+		return ""
+	}
+	s := text[loc[1]:]
+	if loc := reFieldEnd.FindStringIndex(s); loc != nil {
+		s = s[:loc[0]]
+	}
+	return strings.TrimSpace(s)
+}
+
+// infoboxLines returns the cleaned lines of the infobox field, list
+// templates are split into lines.
+func infoboxLines(text, name string) []string {
+	s := reWikiLink.ReplaceAllString(infoboxField(text, name), "$1")
+	if loc := reListTemplate.FindStringIndex(s); loc != nil {
+		items := strings.Replace(s[loc[1]:], "|", "\n", -1)
+		s = s[:loc[0]] + "\n" + strings.TrimSuffix(strings.TrimSpace(items), "}}")
+	}
+
+	var lines []string
+	for _, v := range reBreak.Split(s, -1) {
+		v = strings.TrimLeft(cleanWikiText(v), "*• ")
+		if v != "" {
+			lines = append(lines, v)
+		}
+	}
+	return lines
+}
+
+// builtinOverrides fix extraction of known countries by field, the
+// overrides file takes precedence.
+var builtinOverrides = map[string]map[string]string{
+	"map": {
+		"Czech_Republic":  "EU-Czech_Republic.svg",
+		"Myanmar":         "Myanmar_on_the_globe_(Myanmar_centered).svg",
+		"North_Macedonia": "Europe-Republic_of_North_Macedonia.svg",
+	},
+	"capital": {
+		"Bolivia":           "Sucre *(constitutional and judicial)* and La Paz *(executive and legislative)*",
+		"Azerbaijan":        "Baku",
+		"Equatorial_Guinea": "Malabo *(current) and Ciudad de la Paz *(under construction)*",
+		"Eswatini":          "Mbabane *(executive)* and Lobamba *(legislative)*",
+		"Ivory_Coast":       "Yamoussoukro *(de jure)* and Abidjan *(de facto)*",
+		"Malaysia":          "Kuala Lumpur and Putrajaya *(administrative)*",
+		"South_Africa":      "Pretoria *(executive)*, Cape Town *(legislative)* and Bloemfontein *(judicial)*",
+		"Sri_Lanka":         "Sri Jayawardenepura Kotte *(legislative)* and Colombo *(executive and judicial)*",
+		"Switzerland":       "None *(de jure)* and Bern *(de facto)*",
+		"Yemen":             "Sana'a *(de jure)* and Aden *(Temporary capital)*",
+		"United_States":     "Washington, D.C.",
+	},
+}
+
+// lookupOverride returns the override of the field from the overrides file or
+// the builtin overrides, recording its use.
+func lookupOverride(uname, field string) (string, bool) {
+	x, ok := overrides.get(uname, field)
+	if !ok {
+		x, ok = builtinOverrides[field][uname]
+	}
+	if ok {
+		report.override(uname, field)
+	}
+	return x, ok
+}
+
+// extractCapital parses the infobox capital.
+func extractCapital(text string) (string, error) {
+	v := reCapital.FindStringSubmatch(text)
+	if len(v) != 2 {
+		return "", fmt.Errorf("capital failed %v", v)
+	}
+	return parseWikiLink(v[1]), nil
+}
+
+// fetchCountry fetches the country page following redirects.
+func fetchCountry(name string) (*Source, error) {
+	refresh := isRefresh(name)
+	page, err := getLockedPage(name, toURLName(name), refresh)
+	if err != nil {
+		return nil, err
+	}
+	return &Source{Page: page, Refresh: refresh}, nil
+}
+
+// parseCountry extracts the country from its page, returning nil if the
+// country is filtered out by tags.
+func parseCountry(name string, src *Source) (*Country, error) {
+	page, refresh := src.Page, src.Refresh
+	uname := toURLName(page.Title)
+	var err error
+
+	var mapName, flagName, capital string
+	var warnings []Warning
+	warn := func(field string, msgs ...string) {
+		for _, msg := range msgs {
+			warnings = append(warnings, Warning{Field: field, Message: msg})
+		}
+	}
+
+	text := page.Revisions[0].Text
+
+	// Create Maps
+	if x, ok := lookupOverride(uname, "map"); ok {
+		mapName = x
+	} else {
+		var msgs []string
+		if mapName, msgs, err = extractMapName(text, uname, refresh); err != nil {
+			return nil, fmt.Errorf("%v %w", name, err)
+		}
+		warn("map", msgs...)
+	}
+
+	// Create Flags
+	if x, ok := lookupOverride(uname, "flag"); ok {
+		flagName = x
+	} else {
+		var msgs []string
+		if flagName, msgs, err = extractFlagName(text, uname, refresh); err != nil {
+			return nil, fmt.Errorf("%v %w", name, err)
+		}
+		warn("flag", msgs...)
+	}
+
+	if x, ok := lookupOverride(uname, "capital"); ok {
+		capital = x
+	} else if capital, err = extractCapital(text); err != nil {
+		return nil, fmt.Errorf("%v %w", name, err)
+	}
+
+	var zoomName string
+	if *flagZoomMaps {
+		small, err := needsZoomMap(uname, refresh)
+		if err != nil {
+			return nil, err
+		}
+		if small {
+			zoomName, err = findZoomMap(text, name, mapName, refresh)
+			if err != nil {
+				return nil, err
+			}
+			if zoomName == "" {
+				warn("zoom map", "no zoomed map found")
+				report.fallback(uname, "zoom map")
+			}
+		}
+	}
+
+	var continents []string
+	if *flagLandlocked || *flagLayout == layoutContinent || *flagTags != "" {
+		continents, err = getContinents(uname, refresh)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !*flagRemoteImages {
+		for _, name := range []string{mapName, flagName, zoomName} {
+			if err := sniffFile(name, refresh); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	country := Country{
+		Name:         name,
+		Title:        page.Title,
+		UName:        uname,
+		MapName:      mapName,
+		MapImageURL:  imageURL(mapName),
+		FlagName:     flagName,
+		FlagImageURL: imageURL(flagName),
+		Capital:      capital,
+		Continents:   continents,
+		Warnings:     warnings,
+	}
+	for _, w := range warnings {
+		report.warnf(uname, "%s", w)
+	}
+	country.Article = pageURL(wikipedia, uname)
+	country.Revision = page.Revisions[0].ID
+	country.Files = []string{filePageURL(mapName), filePageURL(flagName)}
+	if err := describeImages(&country, mapName, flagName, refresh); err != nil {
+		return nil, err
+	}
+	if zoomName != "" {
+		country.ZoomMapName = zoomName
+		country.ZoomMapImageURL = imageURL(zoomName)
+		country.Files = append(country.Files, filePageURL(zoomName))
+	}
+	if *flagIncludeIPA {
+		country.IPA = parseIPA(text)
+	}
+	if *flagAltAnswers {
+		e, err := getEntity(uname, refresh)
+		if err != nil {
+			return nil, err
+		}
+		country.AltNames = altNames(e, name)
+
+		capital, err := getCapitalEntity(uname, refresh)
+		if err != nil {
+			return nil, err
+		}
+		if capital != nil {
+			country.CapitalAltNames = altNames(capital, country.Capital)
+		}
+	}
+
+	for _, t := range enabledCardTypes() {
+		if err := t.Extract(&country, src); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Name(), err)
+		}
+	}
+	if err := applyCorrections(&country, refresh); err != nil {
+		return nil, fmt.Errorf("corrections: %w", err)
+	}
+	if *flagNameStyle == nameStyleOfficial || *flagAltAnswers {
+		e, err := getEntity(uname, refresh)
+		if err != nil {
+			return nil, err
+		}
+		applyNameStyle(&country, e)
+	}
+	applyArticle(&country)
+	if err := classify(&country, refresh); err != nil {
+		return nil, fmt.Errorf("classify: %w", err)
+	}
+	if !matchTags(&country) {
+		return nil, nil
+	}
+	if anomalies := sanityCheck(&country); len(anomalies) > 0 {
+		for _, a := range anomalies {
+			report.anomaly(uname, a)
+		}
+		if !*flagAllowAnomaly {
+			return nil, fmt.Errorf("implausible values, review the source page: %s", strings.Join(anomalies, "; "))
+		}
+	}
+	return &country, nil
+}
+
+// renderCountry writes the country's cards of every enabled deck.
+func renderCountry(c *Country, src *Source) error {
+	for _, t := range enabledCardTypes() {
+		if err := t.Render(c, src); err != nil {
+			return fmt.Errorf("%s: %w", t.Name(), err)
+		}
+	}
+	return nil
+}
+
 func run() error {
+	switch *flagDateFormat {
+	case "full", "year":
+	default:
+		return fmt.Errorf("invalid date format %q", *flagDateFormat)
+	}
+	switch *flagLayout {
+	case layoutFlat, layoutContinent, layoutCountry:
+	default:
+		return fmt.Errorf("invalid layout %q", *flagLayout)
+	}
+	switch *flagAltText {
+	case altTemplate, altCommons:
+	default:
+		return fmt.Errorf("invalid alt text source %q", *flagAltText)
+	}
+	switch *flagUnits {
+	case unitsMetric, unitsImperial, unitsBoth:
+	default:
+		return fmt.Errorf("invalid units %q", *flagUnits)
+	}
+	if _, ok := numberSeparators[*flagNumberStyle]; !ok {
+		return fmt.Errorf("invalid number style %q, want one of %s", *flagNumberStyle, numberStyles())
+	}
+	if err := validNameStyle(*flagNameStyle); err != nil {
+		return err
+	}
+	if err := validArticle(*flagArticle); err != nil {
+		return err
+	}
+	switch *flagNaming {
+	case namingWiki, namingSlug:
+	default:
+		return fmt.Errorf("invalid naming policy %q", *flagNaming)
+	}
+	switch *flagDifficulty {
+	case "", difficultyTags, difficultyDirs:
+	default:
+		return fmt.Errorf("invalid difficulty %q", *flagDifficulty)
+	}
+	if *flagPopBuckets != "" {
+		var err error
+		if populationBuckets, err = parseBuckets(*flagPopBuckets); err != nil {
+			return err
+		}
+	}
+	if *flagCapitalMoves {
+		var err error
+		if capitalMoves, err = readCapitalMoves(capitalMovesFile); err != nil {
+			return err
+		}
+	}
+	if err := validMergePolicy(*flagLocMerge); err != nil {
+		return err
+	}
+	if err := validFlagVariant(*flagFlagVariant); err != nil {
+		return err
+	}
+	if *flagDecks != "" {
+		var err error
+		if selectedDecks, err = parseDecks(*flagDecks); err != nil {
+			return err
+		}
+	}
+	if *flagEmojiClues {
+		var err error
+		if emojiClues, err = readEmojiClues(emojiCluesFile); err != nil {
+			return err
+		}
+	}
+	if *flagCorrections != "" {
+		var err error
+		if corrections, err = readCorrections(*flagCorrections); err != nil {
+			return err
+		}
+	}
+	if _, err := dialect(); err != nil {
+		return err
+	}
+	var only *regexp.Regexp
+	if *flagOnly != "" {
+		var err error
+		if only, err = regexp.Compile(*flagOnly); err != nil {
+			return fmt.Errorf("invalid only pattern: %w", err)
+		}
+	}
+
 	// Setup caches
 	os.Mkdir("pages", 0755)
 	os.Mkdir("files", 0755)
+	os.Mkdir(filepath.Join("pages", wiktionary), 0755)
+	os.Mkdir(filepath.Join("pages", wikidata), 0755)
+	os.Mkdir(filepath.Join("pages", wikidataQuery), 0755)
+	os.Mkdir(filepath.Join("pages", commons), 0755)
 
-	page, err := getWikiPage("Member_states_of_the_United_Nations")
+	if err := lock.read(lockFile); err != nil {
+		return err
+	}
+	if err := overrides.read(overridesFile); err != nil {
+		return err
+	}
+	if err := lintTemplates(); err != nil {
+		return err
+	}
+	const members = "Member_states_of_the_United_Nations"
+	page, err := getLockedPage(members, members, *flagRefreshAll)
 	if err != nil {
 		return err
 	}
@@ -292,7 +1164,10 @@ func run() error {
 		}
 
 		sort.Strings(countries)
-		if err := ioutil.WriteFile("countries.txt", []byte(strings.Join(countries, "\n")), 0666); err != nil {
+		if err := checkCountryCount(countries, *flagExpect, "countries.txt"); err != nil {
+			return err
+		}
+		if err := checkCountryList(countries, "countries.txt", *flagAcceptList); err != nil {
 			return err
 		}
 	}
@@ -303,130 +1178,152 @@ func run() error {
 		countries = countries[n:]
 	}
 
-	for idx, name := range countries {
-		uname := toURLName(name)
-		fmt.Println(idx+n, ":", name)
-
-		page, err := getWikiPage(uname)
-		if err != nil {
-			return err
+	var selected []string
+	for _, name := range countries {
+		if only != nil && !only.MatchString(name) {
+			continue
 		}
-
-		// Follow redirects e.g. Bahamas -> The Bahamas.
-		for page.Redir.Title != "" {
-			uname = toURLName(page.Redir.Title)
-
-			page, err = getWikiPage(uname)
-			if err != nil {
-				return err
-			}
+		if *flagLimit > 0 && len(selected) == *flagLimit {
+			break
 		}
+		selected = append(selected, name)
+	}
+	results, err := runPipeline(selected)
+	if err != nil {
+		return err
+	}
 
-		var mapName, flagName, capital string
-
-		// Create Maps
-		if x, ok := map[string]string{
-			"Czech_Republic":  "EU-Czech_Republic.svg",
-			"Myanmar":         "Myanmar_on_the_globe_(Myanmar_centered).svg",
-			"North_Macedonia": "Europe-Republic_of_North_Macedonia.svg",
-			"Eritrea":         "Eritrea_(Africa_orthographic_projection).svg", // Missing "Africa" in wikifile
-			"Iceland":         "Iceland_(orthographic_projection).svg",        // Rename Island -> Iceland
-		}[uname]; ok {
-			mapName = x
-		} else {
-			v := reImageMap.FindStringSubmatch(page.Revisions[0].Text)
-			if len(v) != 2 {
-				v = reImageMap2.FindStringSubmatch(page.Revisions[0].Text)
-				if len(v) != 2 {
-					return fmt.Errorf("%v image map failed %v", name, v)
-				}
+	for _, t := range enabledCardTypes() {
+		if a, ok := t.(Aggregator); ok {
+			if err := a.Aggregate(results); err != nil {
+				return fmt.Errorf("%s: %w", t.Name(), err)
 			}
-			mapName = parseWikiFile(v[1])
 		}
-		if err := makeFile("countries/images", mapName); err != nil {
+	}
+	if *flagOrder {
+		if err := makeOrders(results); err != nil {
 			return err
 		}
-
-		// Create Flags
-		if x, ok := map[string]string{
-			"Federated_States_of_Micronesia": "Flag_of_the_Federated_States_of_Micronesia.svg", // Missing "the"
-			"Honduras":                       "Flag_of_Honduras.svg",                           // Remove "_(darker_variant)"
-			"Seychelles":                     "Flag_of_Seychelles.svg",                         // Remove "the" Seychelles
-		}[uname]; ok {
-			flagName = x
-		} else {
-			v := reImageFlag.FindStringSubmatch(page.Revisions[0].Text)
-			if len(v) != 2 {
-				return fmt.Errorf("%v image flag failed %v", name, v)
-			}
-			flagName = parseWikiFile(v[1])
+	}
+	if *flagIndex {
+		if err := makeIndexes(results); err != nil {
+			return err
 		}
-		if err := makeFile("countries/flags/images", flagName); err != nil {
+	}
+	if *flagLists != "" {
+		if err := makeLists(*flagLists); err != nil {
 			return err
 		}
-
-		if x, ok := map[string]string{
-			"Bolivia":           "Sucre *(constitutional and judicial)* and La Paz *(executive and legislative)*",
-			"Azerbaijan":        "Baku",
-			"Equatorial_Guinea": "Malabo *(current) and Ciudad de la Paz *(under construction)*",
-			"Eswatini":          "Mbabane *(executive)* and Lobamba *(legislative)*",
-			"Ivory_Coast":       "Yamoussoukro *(de jure)* and Abidjan *(de facto)*",
-			"Malaysia":          "Kuala Lumpur and Putrajaya *(administrative)*",
-			"South_Africa":      "Pretoria *(executive)*, Cape Town *(legislative)* and Bloemfontein *(judicial)*",
-			"Sri_Lanka":         "Sri Jayawardenepura Kotte *(legislative)* and Colombo *(executive and judicial)*",
-			"Switzerland":       "None *(de jure)* and Bern *(de facto)*",
-			"Yemen":             "Sana'a *(de jure)* and Aden *(Temporary capital)*",
-			"United_States":     "Washington, D.C.",
-		}[uname]; ok {
-			capital = x
-		} else {
-			v := reCapital.FindStringSubmatch(page.Revisions[0].Text)
-			if len(v) != 2 {
-				return fmt.Errorf("%v capital failed %v", name, v)
-			}
-			capital = parseWikiLink(v[1])
+	}
+	if *flagMemberships != "" {
+		if err := makeMemberships(*flagMemberships, results); err != nil {
+			return err
 		}
-
-		// Load answer for location from card. To difficult to parse
-		// automatically.
-		ansLoc, err := readAnswer("countries", uname+"_location")
-		if err != nil {
+	}
+	if *flagHistorical {
+		if err := makeHistoricalFlags(*flagRefreshAll); err != nil {
 			return err
 		}
-		// Delete images to readd them...
-		if strings.Contains(ansLoc, "![") {
-			ansLoc = strings.Split(ansLoc, "![")[0]
-			ansLoc = strings.TrimSpace(ansLoc)
+	}
+	// Releases need every country, a partial run would remove the rest.
+	partial := *flagCountry != "" || *flagPosition > 0 || *flagLimit > 0 ||
+		*flagOnly != "" || *flagTags != "" || *flagDecks != "" || len(results) < len(countries)
+	if *flagJSON {
+		if err := writeCountries(results); err != nil {
+			return err
 		}
-
-		country := Country{
-			Name:           name,
-			MapImageURL:    "images/" + mapName,
-			FlagImageURL:   "images/" + flagName,
-			Capital:        capital,
-			AnswerLocation: ansLoc,
+	}
+	if *flagGenGo != "" {
+		if err := genDataPackage(*flagGenGo, results); err != nil {
+			return err
 		}
-
-		// Render the different files.
-		if err := makeTmpl("countries", uname+"_location", "location", &country); err != nil {
+	}
+	if !*flagLocked {
+		if err := lock.write(lockFile); err != nil {
 			return err
 		}
-		if err := makeTmpl("countries", uname, "world", &country); err != nil {
+	}
+	if *flagRelease && !partial {
+		if err := release(results); err != nil {
 			return err
 		}
-		if err := makeTmpl(filepath.Join("countries", "flags"), uname, "flag", &country); err != nil {
+	}
+	if *flagMediaStore != "" && !*flagRemoteImages {
+		if err := writeMediaStore("countries"); err != nil {
 			return err
 		}
-		if err := makeTmpl(filepath.Join("countries", "capitals"), uname, "capital", &country); err != nil {
+	}
+	if *flagStats && !*flagCheck {
+		if err := writeStats("countries"); err != nil {
 			return err
 		}
 	}
-	return nil
+	return checkResult()
 }
 
 func main() {
 	flag.Parse()
-	if err := run(); err != nil {
+
+	err := setupClient()
+	if err == nil {
+		err = setupLimiters()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	switch cmd := flag.Arg(0); cmd {
+	case "", "sync":
+		if *flagPprof != "" {
+			servePprof(*flagPprof)
+		}
+		if *flagAudit {
+			err = auditOverrides(os.Stdout)
+			break
+		}
+		err = run()
+		if err == nil && cmd == "sync" {
+			err = syncDecks("countries")
+		}
+		if *flagMetrics {
+			metrics.print(os.Stderr)
+		}
+		if *flagOpenFailures {
+			if oerr := openFailures(os.Stdout); oerr != nil && err == nil {
+				err = oerr
+			}
+		}
+		if *flagReport != "" {
+			if rerr := report.write(*flagReport); rerr != nil && err == nil {
+				err = rerr
+			}
+		}
+	case "serve":
+		err = serve(*flagAddr)
+	case "review":
+		err = review(os.Stdin, os.Stdout)
+	case "schema":
+		err = schemaCommand()
+	case "upload":
+		root := flag.Arg(2)
+		if root == "" {
+			root = "countries"
+		}
+		err = upload(flag.Arg(1), root)
+	case "check-links":
+		err = checkLinks("countries")
+	case "export":
+		err = exportDecks(flag.Arg(1), "countries")
+	case "cache":
+		if sub := flag.Arg(1); sub != "stats" {
+			err = fmt.Errorf("unknown cache command %q", sub)
+			break
+		}
+		err = cacheStats(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown command %q", cmd)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReCountry(t *testing.T) {
+	text := `{{Flagicon|Guinea-Bissau}} [[Guinea-Bissau]]
+{{Flagicon|São Tomé and Príncipe}} [[São Tomé and Príncipe]]
+{{flagicon|Costa Rica}} [[Costa Rica]]
+{{Flagicon|Georgia}} [[Georgia (country)|Georgia]]`
+	var got []string
+	for _, v := range reCountry.FindAllStringSubmatch(text, -1) {
+		got = append(got, v[1])
+	}
+	want := []string{"Guinea-Bissau", "São Tomé and Príncipe", "Costa Rica", "Georgia (country)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAltNames(t *testing.T) {
+	e := &Entity{
+		Labels: map[string]wdText{"en": {Value: "Czech Republic"}},
+		Aliases: map[string][]wdText{"en": {
+			{Value: "Czechia"},
+			{Value: "CZ"},
+			{Value: "CZE"},
+			{Value: "🇨🇿"},
+			{Value: "czechia"},
+			{Value: "ČR"},
+			{Value: "Česko"},
+		}},
+	}
+	got := altNames(e, "Czech Republic")
+	want := []string{"Czechia", "Česko"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseWikiFile(t *testing.T) {
+	tests := []struct {
+		in       string
+		want     string
+		warnings int
+	}{
+		{"Flag of Ghana.svg", "Flag_of_Ghana.svg", 0},
+		{"File:Flag of Ghana.svg", "Flag_of_Ghana.svg", 0},
+		{"[[File:Ghana map.png|250px]]", "Ghana_map.png", 0},
+		{"[[File:Ghana map.png]] and [[File:Other.png]]", "Ghana_map.png", 0},
+		{"[[File:Ghana map.png", "Ghana_map.png", 1},
+		{"[[File:", "", 1},
+		{"Ghana map.png{{!}}upright=1.2", "Ghana_map.png", 0},
+		{"{{!}}", "", 0},
+		{"Ghana map.png <span", "Ghana_map.png", 1},
+		{"Ghana > map.png", "Ghana", 1},
+		{"<", "", 1},
+		{">", "", 1},
+		{"Map.svg<!-- unterminated", "Map.svg", 1},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		got, warnings := parseWikiFile(tt.in)
+		if got != tt.want || len(warnings) != tt.warnings {
+			t.Errorf("parseWikiFile(%q) = %q, %q, want %q with %d warnings", tt.in, got, warnings, tt.want, tt.warnings)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// defaultMapPatterns are the commons map naming patterns in order of
+// preference.
+const defaultMapPatterns = "(orthographic projection).svg,on the globe,in its region"
+
+// mapPatterns returns the --map-patterns in order of preference.
+func mapPatterns() []string {
+	var patterns []string
+	for _, p := range strings.Split(*flagMapPatterns, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// matchMapPattern reports whether the commons file name matches the
+// pattern, ignoring case and underscores.
+func matchMapPattern(name, pattern string) bool {
+	return strings.Contains(strings.ToLower(toURLName(name)), strings.ToLower(toURLName(pattern)))
+}
+
+// mapCandidate is an infobox map file with its parse warnings.
+type mapCandidate struct {
+	name string
+	msgs []string
+}
+
+// infoboxMaps parses the infobox map files, image_map then image_map2.
+func infoboxMaps(text string) []mapCandidate {
+	var maps []mapCandidate
+	for _, v := range reImageMap.FindAllStringSubmatch(text, -1) {
+		name, msgs := parseWikiFile(v[1])
+		if name != "" {
+			maps = append(maps, mapCandidate{name, msgs})
+		}
+	}
+	return maps
+}
+
+// commonsMapNames are the conventional commons maps of the country, probed
+// when no infobox map matches a pattern.
+func commonsMapNames(uname string) []string {
+	return []string{
+		uname + "_(orthographic_projection).svg",
+		uname + "_on_the_globe_(" + uname + "_centered).svg",
+		uname + "_in_its_region.svg",
+	}
+}
+
+// fileExists reports whether the commons file exists, asking the API
+// rather than downloading it. Redirects don't exist as they can't be
+// downloaded by name.
+func fileExists(name string, refresh bool) (bool, error) {
+	fname := filepath.Join("pages", commons, "exists", name+".json")
+	params := url.Values{
+		"action":        {"query"},
+		"titles":        {"File:" + name},
+		"prop":          {"info"},
+		"format":        {"json"},
+		"formatversion": {"2"},
+		"maxlag":        {maxLag},
+	}
+	body, err := fetcher.Query(fname, "https://"+commons+"/w/api.php?"+params.Encode(), refresh)
+	if err != nil {
+		return false, err
+	}
+
+	var rsp struct {
+		Query struct {
+			Pages []struct {
+				Missing  bool `json:"missing"`
+				Invalid  bool `json:"invalid"`
+				Redirect bool `json:"redirect"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	if err := json.Unmarshal(body, &rsp); err != nil {
+		return false, fmt.Errorf("%s: %w", fname, err)
+	}
+	for _, p := range rsp.Query.Pages {
+		if !p.Missing && !p.Invalid && !p.Redirect {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// extractMapName selects the map falling back through the patterns: the
+// first existing infobox map matching a pattern, else the first existing
+// commons map matching it. Without a match the first existing infobox map is
+// used, then the wikidata locator map.
+func extractMapName(text, uname string, refresh bool) (string, []string, error) {
+	var maps []mapCandidate
+	for _, m := range infoboxMaps(text) {
+		ok, err := fileExists(m.name, refresh)
+		if err != nil {
+			return "", nil, err
+		}
+		if ok {
+			maps = append(maps, m)
+		}
+	}
+	for _, p := range mapPatterns() {
+		for _, m := range maps {
+			if matchMapPattern(m.name, p) {
+				return m.name, m.msgs, nil
+			}
+		}
+		for _, name := range commonsMapNames(uname) {
+			if !matchMapPattern(name, p) {
+				continue
+			}
+			ok, err := fileExists(name, refresh)
+			if err != nil {
+				return "", nil, err
+			}
+			if ok {
+				return name, nil, nil
+			}
+		}
+	}
+	if len(maps) > 0 {
+		return maps[0].name, maps[0].msgs, nil
+	}
+	name, err := locatorMapName(uname, refresh)
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		return "", nil, fmt.Errorf("image map failed: no usable infobox or locator map")
+	}
+	report.fallback(uname, "locator map")
+	return name, []string{"no usable infobox map, using the wikidata locator map"}, nil
+}
+
+// locatorMapName returns the wikidata locator map, preferring the patterns,
+// empty if none exist.
+func locatorMapName(uname string, refresh bool) (string, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, v := range e.Strings("P242") { // locator map image
+		name := commonsFileName(v)
+		ok, err := fileExists(name, refresh)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			names = append(names, name)
+		}
+	}
+	for _, p := range mapPatterns() {
+		for _, name := range names {
+			if matchMapPattern(name, p) {
+				return name, nil
+			}
+		}
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+	return names[0], nil
+}
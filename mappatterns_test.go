@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestFileExists(t *testing.T) {
+	withFetcher(t, &memFetcher{Queries: map[string][]byte{
+		existsKey("Ghana_(orthographic_projection).svg"): []byte(`{"query":{"pages":[{"pageid":1,"title":"File:Ghana (orthographic projection).svg"}]}}`),
+		existsKey("Ghana_in_its_region.svg"):             []byte(`{"query":{"pages":[{"title":"File:Ghana in its region.svg","missing":true}]}}`),
+		existsKey("Ghana_on_the_globe.svg"):              []byte(`{"query":{"pages":[{"pageid":2,"title":"File:Ghana on the globe.svg","redirect":true}]}}`),
+	}})
+	tests := map[string]bool{
+		"Ghana_(orthographic_projection).svg": true,
+		"Ghana_in_its_region.svg":             false,
+		"Ghana_on_the_globe.svg":              false,
+	}
+	for name, want := range tests {
+		got, err := fileExists(name, false)
+		if err != nil {
+			t.Errorf("fileExists(%s): %v", name, err)
+		} else if got != want {
+			t.Errorf("fileExists(%s) = %v, want %v", name, got, want)
+		}
+	}
+	// A failed query isn't a missing file.
+	if _, err := fileExists("Ghana_unknown.svg", false); err == nil {
+		t.Error("fileExists(Ghana_unknown.svg): query failure not returned")
+	}
+}
+
+func TestExtractMapNameQueryFailed(t *testing.T) {
+	// The orthographic map can't be checked, the regional one is never
+	// chosen in its place.
+	withFetcher(t, &memFetcher{Queries: map[string][]byte{
+		existsKey("Ghana_in_its_region.svg"): []byte(`{"query":{"pages":[{"pageid":1,"title":"File:Ghana in its region.svg"}]}}`),
+	}})
+	text := "| image_map = Ghana (orthographic projection).svg\n| image_map2 = Ghana in its region.svg\n"
+	if name, _, err := extractMapName(text, "Ghana", false); err == nil {
+		t.Errorf("extractMapName = %q, want query error", name)
+	}
+}
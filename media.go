@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// sniffLen is the prefix read to sniff a file's content type.
+const sniffLen = 512
+
+// mediaExts maps sniffed content types to file extensions.
+var mediaExts = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/svg+xml":   ".svg",
+	"application/ogg": ".ogg",
+	"audio/ogg":       ".ogg",
+	"audio/mpeg":      ".mp3",
+	"audio/wave":      ".wav",
+}
+
+// sniffType returns the content type of the file prefix, recognising svg
+// which http.DetectContentType reports as xml or text, or html when it
+// starts with a comment.
+func sniffType(b []byte) string {
+	t := http.DetectContentType(b)
+	if strings.HasPrefix(t, "text/") && strings.Contains(string(b), "<svg") {
+		return "image/svg+xml"
+	}
+	if i := strings.Index(t, ";"); i > -1 {
+		t = t[:i]
+	}
+	return t
+}
+
+// rejectHTML errors if the response is an html page, e.g. an error page
+// served in place of the file. The response's content type is trusted over
+// sniffing, the html error pages of commons may include svg icons.
+func rejectHTML(name, contentType string, r io.ReadCloser) (io.ReadCloser, error) {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		if t == "text/html" {
+			r.Close()
+			return nil, fmt.Errorf("%s: got html instead of the file", name)
+		}
+		if _, ok := mediaExts[t]; ok {
+			return r, nil
+		}
+	}
+	br := bufio.NewReaderSize(r, sniffLen)
+	b, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		r.Close()
+		return nil, err
+	}
+	if sniffType(b) == "text/html" {
+		r.Close()
+		return nil, fmt.Errorf("%s: got html instead of the file", name)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{br, r}, nil
+}
+
+// fixExt corrects the extension of the file name to match its content,
+// lowercasing it and appending one if missing, e.g. Flag.SVG -> Flag.svg.
+// Unknown content types keep the name.
+func fixExt(name string, b []byte) string {
+	want, ok := mediaExts[sniffType(b)]
+	if !ok {
+		return name
+	}
+	ext := filepath.Ext(name)
+	switch strings.ToLower(ext) {
+	case want:
+		return strings.TrimSuffix(name, ext) + want
+	case ".jpeg":
+		if want == ".jpg" {
+			return strings.TrimSuffix(name, ext) + ".jpeg"
+		}
+	}
+	return name + want
+}
+
+// localNames maps commons file names to their stored file names, after
+// extension correction.
+var localNames sync.Map
+
+// localName returns the stored file name of the commons file.
+func localName(name string) string {
+	if v, ok := localNames.Load(name); ok {
+		return v.(string)
+	}
+	return safeFileName(name)
+}
+
+// setLocalName records the stored name of the file from its content.
+func setLocalName(name string, b []byte) string {
+	local := safeFileName(fixExt(name, b))
+	localNames.Store(name, local)
+	return local
+}
+
+// sniffFile records the stored name of the file before it is written, so
+// cards can reference it.
+func sniffFile(name string, refresh bool) error {
+	if _, ok := localNames.Load(name); ok || name == "" {
+		return nil
+	}
+	r, err := getFile(name, refresh)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	b := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	setLocalName(name, b[:n])
+	return nil
+}
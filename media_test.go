@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestSniffType(t *testing.T) {
+	tests := []struct {
+		b    string
+		want string
+	}{
+		{`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"/>`, "image/svg+xml"},
+		{`<svg xmlns="http://www.w3.org/2000/svg"/>`, "image/svg+xml"},
+		{"<!-- Created with Inkscape -->\n<svg xmlns=\"http://www.w3.org/2000/svg\"/>", "image/svg+xml"},
+		{"<!DOCTYPE html><html><body>Not Found</body></html>", "text/html"},
+		{pngHeader, "image/png"},
+	}
+	for _, tt := range tests {
+		if got := sniffType([]byte(tt.b)); got != tt.want {
+			t.Errorf("sniffType(%q) = %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestRejectHTML(t *testing.T) {
+	const (
+		commentSVG = "<!-- Created with Inkscape -->\n<svg xmlns=\"http://www.w3.org/2000/svg\"/>"
+		errorPage  = "<!DOCTYPE html><html><body><svg class=\"icon\"/>Not Found</body></html>"
+	)
+	tests := []struct {
+		contentType string
+		body        string
+		reject      bool
+	}{
+		{"image/svg+xml", commentSVG, false},
+		{"", commentSVG, false},
+		{"application/octet-stream", commentSVG, false},
+		{"text/html; charset=utf-8", errorPage, true},
+		{"text/html; charset=utf-8", commentSVG, true},
+		{"", "<!DOCTYPE html><html><body>Not Found</body></html>", true},
+	}
+	for _, tt := range tests {
+		r, err := rejectHTML("Flag.svg", tt.contentType, ioutil.NopCloser(strings.NewReader(tt.body)))
+		if (err != nil) != tt.reject {
+			t.Errorf("rejectHTML(%q, %.20q) error %v, want rejected %v", tt.contentType, tt.body, err, tt.reject)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil || string(b) != tt.body {
+			t.Errorf("rejectHTML(%q) body %q, %v", tt.contentType, b, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The media store holds media named by content hash, cards referencing it
+// never break when commons files are renamed or updated.
+const (
+	mediaStoreDir      = "media"
+	mediaStoreManifest = "manifest.json"
+)
+
+var reContentName = regexp.MustCompile(`^[0-9a-f]{64}(\.[a-z0-9]+)?$`)
+
+// MediaStore is the store manifest, mapping deck media paths to their
+// content addressed names under Base.
+type MediaStore struct {
+	Base  string            `json:"base"`
+	Files map[string]string `json:"files"` // e.g. flags/images/Flag_of_Chad.svg
+}
+
+// mediaHashes maps the written media paths to their content addressed
+// names.
+var mediaHashes sync.Map
+
+// contentName names the file by the sha256 of its content, keeping the
+// extension for content types.
+func contentName(name string, b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]) + strings.ToLower(filepath.Ext(name))
+}
+
+// writeMedia writes the media file to its deck, after the --post-render
+// hook, and with --media-store to the store. Stored files are immutable so never rewritten.
+func writeMedia(path string, b []byte) error {
+	b, err := postRender("media", path, b)
+	if err != nil {
+		return err
+	}
+	if err := writeOutput(path, b); err != nil {
+		return err
+	}
+	if *flagMediaStore == "" {
+		return nil
+	}
+	name := contentName(path, b)
+	mediaHashes.Store(filepath.Clean(path), name)
+	stored := filepath.Join(mediaStoreDir, name)
+	if _, err := os.Stat(stored); err == nil {
+		return nil
+	}
+	return writeOutput(stored, b)
+}
+
+// contentAddress rewrites the card's references to media in dir with their
+// store urls.
+func contentAddress(dir, card string) string {
+	if *flagMediaStore == "" {
+		return card
+	}
+	base := strings.TrimSuffix(*flagMediaStore, "/") + "/"
+	resolve := func(ref string) string {
+		name, err := url.PathUnescape(ref)
+		if err != nil {
+			name = ref
+		}
+		if v, ok := mediaHashes.Load(filepath.Join(dir, filepath.FromSlash(name))); ok {
+			return base + v.(string)
+		}
+		return ref
+	}
+	card = reMarkdownImage.ReplaceAllStringFunc(card, func(m string) string {
+		v := reMarkdownImage.FindStringSubmatch(m)
+		return "![" + v[1] + "](" + resolve(v[2]) + ")"
+	})
+	return reSrcRef.ReplaceAllStringFunc(card, func(m string) string {
+		return `src="` + resolve(reSrcRef.FindStringSubmatch(m)[1]) + `"`
+	})
+}
+
+// writeMediaStore writes the store manifest of the media under root,
+// merged with the existing manifest so partial runs keep the rest.
+func writeMediaStore(root string) error {
+	path := filepath.Join(mediaStoreDir, mediaStoreManifest)
+	m := MediaStore{Files: make(map[string]string)}
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(b, &m); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if m.Base != *flagMediaStore {
+		m.Files = make(map[string]string) // urls moved
+	}
+	m.Base = *flagMediaStore
+
+	var rerr error
+	mediaHashes.Range(func(k, v interface{}) bool {
+		var rel string
+		rel, rerr = filepath.Rel(root, k.(string))
+		m.Files[filepath.ToSlash(rel)] = v.(string)
+		return rerr == nil
+	})
+	if rerr != nil {
+		return rerr
+	}
+	b, err = json.MarshalIndent(&m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(path, append(b, '\n'))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestContentAddress(t *testing.T) {
+	old := *flagMediaStore
+	*flagMediaStore = "https://example.org/media"
+	defer func() { *flagMediaStore = old }()
+
+	hash := contentName("map.svg", []byte("<svg/>"))
+	for _, name := range []string{
+		"Chad_(orthographic_projection).svg",
+		"Côte_d'Ivoire_(orthographic_projection).svg",
+		"Flag_of_Chad.svg",
+	} {
+		path := filepath.Join("countries", "images", name)
+		mediaHashes.Store(path, hash)
+		defer mediaHashes.Delete(path)
+	}
+
+	want := "https://example.org/media/" + hash
+	tests := map[string]string{
+		"![Chad](images/Chad_(orthographic_projection).svg)":              "![Chad](" + want + ")",
+		"![](images/C%C3%B4te_d%27Ivoire_(orthographic_projection).svg)":  "![](" + want + ")",
+		"![Flag](images/Flag_of_Chad.svg) and ![Map](images/Missing.svg)": "![Flag](" + want + ") and ![Map](images/Missing.svg)",
+		`<audio controls src="images/Flag_of_Chad.svg">`:                  `<audio controls src="` + want + `">`,
+	}
+	for in, want := range tests {
+		if got := contentAddress("countries", in); got != want {
+			t.Errorf("contentAddress(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// MembershipSpec scrapes the member countries of an organisation from the
+// wikitables of a wikipedia page.
+type MembershipSpec struct {
+	Name  string // deck name
+	Page  string
+	Title string // organisation, e.g. "the European Union"
+}
+
+var membershipSpecs = []MembershipSpec{{
+	Name:  "eu",
+	Page:  "Member_state_of_the_European_Union",
+	Title: "the European Union",
+}, {
+	Name:  "schengen",
+	Page:  "Schengen_Area",
+	Title: "the Schengen Area",
+}, {
+	Name:  "eurozone",
+	Page:  "Eurozone",
+	Title: "the eurozone",
+}, {
+	Name:  "commonwealth",
+	Page:  "Member_states_of_the_Commonwealth_of_Nations",
+	Title: "the Commonwealth of Nations",
+}, {
+	Name:  "francophonie",
+	Page:  "Organisation_internationale_de_la_Francophonie",
+	Title: "the Organisation internationale de la Francophonie",
+}}
+
+// membershipMaxAge is how long membership pages are cached before being
+// refetched, so enlargements are picked up.
+const membershipMaxAge = 7 * 24 * time.Hour
+
+// Membership is a single "Is X a member?" card.
+type Membership struct {
+	Provenance
+
+	Country string
+	Title   string
+	Member  bool
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("membership").Parse(`Is **{{.Country}}** a member of {{.Title}}?
+<!--question-->
+**{{if .Member}}Yes{{else}}No{{end}}**`))
+}
+
+// isStale reports whether the cache file at path, compressed or not, is
+// missing or older than maxAge.
+func isStale(path string, maxAge time.Duration) bool {
+	for _, p := range []string{path + gzExt, path} {
+		if info, err := os.Stat(p); err == nil {
+			return time.Since(info.ModTime()) > maxAge
+		}
+	}
+	return true
+}
+
+// [[Target|Text]], {{flag|Target}}
+var reRowTarget = regexp.MustCompile(`\[\[([^\]|#]+)|(?i){{\s*(?:flag|flagcountry|flagu)\s*\|\s*([^}|]+)`)
+
+// rowTargets returns the link targets of the row's cells in order, files
+// and other namespaces are skipped.
+func rowTargets(row []string) []string {
+	var targets []string
+	for _, cell := range row {
+		for _, v := range reRowTarget.FindAllStringSubmatch(cell, -1) {
+			target := strings.TrimSpace(v[1] + v[2])
+			if target != "" && !strings.Contains(target, ":") {
+				targets = append(targets, target)
+			}
+		}
+	}
+	return targets
+}
+
+// matchRow returns the url name of the known country listed in the row by
+// a link target or cell text, empty if none. Redirecting link targets, e.g.
+// Czech Republic, are resolved with resolve.
+func matchRow(row []string, known map[string]bool, resolve, refresh bool) (string, error) {
+	targets := rowTargets(row)
+	for _, target := range targets {
+		if uname := toURLName(target); known[uname] {
+			return uname, nil
+		}
+	}
+	for _, cell := range row {
+		if uname := toURLName(cleanWikiCell(cell)); known[uname] {
+			return uname, nil
+		}
+	}
+	if !resolve {
+		return "", nil
+	}
+	for _, target := range targets {
+		uname, err := resolveTitle(toURLName(target), refresh)
+		if err != nil {
+			return "", err
+		}
+		if known[uname] {
+			return uname, nil
+		}
+	}
+	return "", nil
+}
+
+// rowLabel returns the first non-empty cell of the row for warnings.
+func rowLabel(row []string) string {
+	for _, cell := range row {
+		if s := cleanWikiCell(cell); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// getMembers returns the url names of the known countries listed in the
+// page's wikitables. Pages often also list candidates or former members, so
+// the table listing the most countries is used. Its rows not matched
+// directly are resolved through redirects, rows still unmatched are warned
+// about as the country's card would answer no.
+func getMembers(spec MembershipSpec, unames []string, refresh bool) ([]string, *Provenance, error) {
+	resolveRefresh := refresh
+	refresh = refresh || isStale(pagePath(wikipedia, spec.Page), membershipMaxAge)
+	page, err := getLockedWikiPage(wikipedia, spec.Page, refresh)
+	if err != nil {
+		return nil, nil, err
+	}
+	known := make(map[string]bool, len(unames))
+	for _, uname := range unames {
+		known[uname] = true
+	}
+
+	var best []string
+	var bestRows [][]string
+	for _, table := range strings.Split(page.Revisions[0].Text, "{|")[1:] {
+		rows := wikiTableRows("{|" + table)
+		var found []string
+		for _, row := range rows {
+			uname, err := matchRow(row, known, false, resolveRefresh)
+			if err != nil {
+				return nil, nil, err
+			}
+			if uname != "" {
+				found = append(found, uname)
+			}
+		}
+		if len(found) > len(best) {
+			best, bestRows = found, rows
+		}
+	}
+	if len(best) == 0 {
+		return nil, nil, fmt.Errorf("%s: no members found in %s", spec.Name, spec.Page)
+	}
+
+	var members []string
+	seen := make(map[string]bool)
+	for _, row := range bestRows {
+		uname, err := matchRow(row, known, true, resolveRefresh)
+		if err != nil {
+			return nil, nil, err
+		}
+		if uname == "" {
+			report.warnf(spec.Name, "%s: listed %q matches no country", spec.Page, rowLabel(row))
+			continue
+		}
+		if !seen[uname] {
+			seen[uname] = true
+			members = append(members, uname)
+		}
+	}
+	sort.Strings(members)
+	p := &Provenance{
+		Article:  pageURL(wikipedia, spec.Page),
+		Revision: page.Revisions[0].ID,
+	}
+	return members, p, nil
+}
+
+// makeMemberships renders the named membership decks, e.g. "eu,schengen".
+// Each deck lists the members, with a card per country on a continent with
+// members.
+func makeMemberships(names string, countries []Country) error {
+	// Countries are matched by url name, their card names follow
+	// --name-style and --article.
+	var known []string
+	byName := make(map[string]*Country, len(countries))
+	for i := range countries {
+		known = append(known, countries[i].UName)
+		byName[countries[i].UName] = &countries[i]
+	}
+
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		var spec *MembershipSpec
+		for i := range membershipSpecs {
+			if membershipSpecs[i].Name == name {
+				spec = &membershipSpecs[i]
+			}
+		}
+		if spec == nil {
+			return fmt.Errorf("unknown membership deck %q", name)
+		}
+
+		members, prov, err := getMembers(*spec, known, *flagRefreshAll)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join("countries", "memberships", spec.Name)
+
+		isMember := make(map[string]bool, len(members))
+		continents := make(map[string]bool)
+		var list []*Country
+		for _, m := range members {
+			isMember[m] = true
+			c := byName[m]
+			list = append(list, c)
+			for _, continent := range c.Continents {
+				continents[continent] = true
+			}
+		}
+		g := CountryGroups{
+			Question: fmt.Sprintf("Which countries are members of %s?", spec.Title),
+			Groups:   groupByContinent(list),
+		}
+		if err := makeTmpl(dir, "members", "country-groups", &g); err != nil {
+			return err
+		}
+
+		sort.Strings(known)
+		for _, n := range known {
+			c := byName[n]
+			near := isMember[n]
+			for _, continent := range c.Continents {
+				near = near || continents[continent]
+			}
+			if !near {
+				continue
+			}
+			m := Membership{
+				Provenance: *prov,
+				Country:    c.Name,
+				Title:      spec.Title,
+				Member:     isMember[n],
+			}
+			if err := makeTmpl(dir, countryFileName(c), "membership", &m); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetMembers(t *testing.T) {
+	const text = `Members are listed below.
+{| class="wikitable sortable"
+! Country !! Accession
+|-
+| {{flag|Austria}} || 1995
+|-
+| [[File:Flag of the Czech Republic.svg|20px]] [[Czech Republic]] || 2004
+|-
+| [[Kingdom of the Netherlands|Netherlands]] || 1958
+|-
+| style="text-align:left" | [[Republic of Ireland|Ireland]]<ref>Note</ref> || 1973
+|-
+| [[Atlantis]] || 2020
+|}
+
+Candidates:
+{| class="wikitable"
+! Country
+|-
+| [[Albania]]
+|}`
+	titles := func(uname, title string) (string, []byte) {
+		return filepath.Join("pages", wikipedia, "titles", uname+".json"),
+			[]byte(`{"query":{"pages":[{"title":"` + title + `"}]}}`)
+	}
+	f := &memFetcher{
+		Pages:   map[string][]byte{wikipedia + "/Member_state_of_the_European_Union": exportXML("Member state of the European Union", text)},
+		Queries: make(map[string][]byte),
+	}
+	k, v := titles("Czech_Republic", "Czechia")
+	f.Queries[k] = v
+	k, v = titles("Atlantis", "Atlantis (mythology)")
+	f.Queries[k] = v
+	withFetcher(t, f)
+	oldWarnings := report.Warnings
+	defer func() { report.Warnings = oldWarnings }()
+	report.Warnings = nil
+
+	known := []string{"Albania", "Austria", "Czechia", "Ghana", "Kingdom_of_the_Netherlands", "Republic_of_Ireland"}
+	members, p, err := getMembers(membershipSpecs[0], known, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Austria", "Czechia", "Kingdom_of_the_Netherlands", "Republic_of_Ireland"}
+	if !reflect.DeepEqual(members, want) {
+		t.Errorf("members %q, want %q", members, want)
+	}
+	if p.Revision != 42 {
+		t.Errorf("revision %d, want 42", p.Revision)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], `"Atlantis"`) {
+		t.Errorf("warnings %q, want the unmatched Atlantis row", report.Warnings)
+	}
+}
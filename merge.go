@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Location answer merge policies, how the hand written answer is combined
+// with the generated maps.
+const (
+	mergeKeepManual    = "keep-manual"    // the answer is used as written, no maps are added
+	mergeReplaceImages = "replace-images" // images in the answer are replaced by the maps
+	mergeAppend        = "append"         // maps missing from the answer are appended
+)
+
+func validMergePolicy(policy string) error {
+	switch policy {
+	case mergeKeepManual, mergeReplaceImages, mergeAppend:
+		return nil
+	}
+	return fmt.Errorf("invalid location merge policy %q, want %s, %s or %s", policy, mergeKeepManual, mergeReplaceImages, mergeAppend)
+}
+
+// isImageParagraph reports whether the paragraph is only markdown images.
+func isImageParagraph(p string) bool {
+	p = strings.TrimSpace(p)
+	return p != "" && strings.TrimSpace(reMarkdownImage.ReplaceAllString(p, "")) == ""
+}
+
+// mergeAnswer returns the text of the hand written answer kept by the
+// policy. Replacing images drops the paragraphs that are only images, text
+// mentioning "![" is kept.
+func mergeAnswer(policy, ans string) string {
+	if policy != mergeReplaceImages {
+		return strings.TrimSpace(ans)
+	}
+	var kept []string
+	for _, p := range strings.Split(ans, "\n\n") {
+		if !isImageParagraph(p) {
+			kept = append(kept, strings.TrimSpace(p))
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n\n"))
+}
+
+// mergeMaps returns the generated map images to add after the answer.
+func mergeMaps(policy string, c *Country) []string {
+	if policy == mergeKeepManual {
+		return nil
+	}
+	images := []string{"![" + c.MapAlt + "](" + c.MapImageURL + ")"}
+	if c.ZoomMapImageURL != "" {
+		images = append(images, "![Zoomed map of "+c.Name+"]("+c.ZoomMapImageURL+")")
+	}
+	if policy == mergeReplaceImages {
+		return images
+	}
+	var missing []string
+	for _, img := range images {
+		url := reMarkdownImage.FindStringSubmatch(img)[2]
+		if !strings.Contains(c.AnswerLocation, "]("+url+")") {
+			missing = append(missing, img)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeAnswer(t *testing.T) {
+	const (
+		prose = "Western Africa, on the Gulf of Guinea.\n\nWrite ![ to embed an image."
+		ans   = "Western Africa, on the Gulf of Guinea.\n\n![Map](images/Old.svg)\n\nWrite ![ to embed an image."
+	)
+	tests := []struct {
+		policy, ans, want string
+	}{
+		{mergeKeepManual, ans, ans},
+		{mergeReplaceImages, ans, prose},
+		{mergeAppend, ans, ans},
+		{mergeReplaceImages, prose, prose},
+		{mergeReplaceImages, "![A](a.svg) ![B](b.svg)\n\nText", "Text"},
+		{mergeReplaceImages, "  Text  \n\n![A](a.svg)\n", "Text"},
+	}
+	for _, tt := range tests {
+		if got := mergeAnswer(tt.policy, tt.ans); got != tt.want {
+			t.Errorf("mergeAnswer(%s, %q) = %q, want %q", tt.policy, tt.ans, got, tt.want)
+		}
+	}
+}
+
+func TestMergeMaps(t *testing.T) {
+	const (
+		mapImg  = "![Map of Ghana](images/Ghana.svg)"
+		zoomImg = "![Zoomed map of Ghana](images/Ghana_zoom.svg)"
+	)
+	tests := []struct {
+		policy string
+		answer string
+		zoom   bool
+		want   []string
+	}{
+		{mergeKeepManual, "Africa", true, nil},
+		{mergeReplaceImages, "Africa", false, []string{mapImg}},
+		{mergeReplaceImages, "Africa", true, []string{mapImg, zoomImg}},
+		{mergeReplaceImages, "Africa\n\n" + mapImg, false, []string{mapImg}},
+		{mergeAppend, "Africa", true, []string{mapImg, zoomImg}},
+		{mergeAppend, "Africa\n\n" + mapImg, true, []string{zoomImg}},
+		{mergeAppend, "Africa\n\n![Hand drawn](images/Ghana.svg)", false, nil},
+		{mergeAppend, "Write ![ to embed an image.", false, []string{mapImg}},
+	}
+	for _, tt := range tests {
+		c := &Country{
+			Name:           "Ghana",
+			MapAlt:         "Map of Ghana",
+			MapImageURL:    "images/Ghana.svg",
+			AnswerLocation: tt.answer,
+		}
+		if tt.zoom {
+			c.ZoomMapImageURL = "images/Ghana_zoom.svg"
+		}
+		if got := mergeMaps(tt.policy, c); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mergeMaps(%s, %q) = %q, want %q", tt.policy, tt.answer, got, tt.want)
+		}
+	}
+}
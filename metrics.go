@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	_ "net/http/pprof" // registers /debug/pprof handlers
+	"sort"
+	"sync"
+	"time"
+)
+
+// Metrics records durations to diagnose performance and tune the stage
+// concurrency and rate limits.
+type Metrics struct {
+	mu   sync.Mutex
+	obs  map[string][]time.Duration
+	keys []string // in first observed order
+}
+
+var metrics = Metrics{obs: make(map[string][]time.Duration)}
+
+// observe records a duration of the kind, e.g. "request" or "parse".
+func (m *Metrics) observe(kind string, d time.Duration) {
+	m.mu.Lock()
+	if _, ok := m.obs[kind]; !ok {
+		m.keys = append(m.keys, kind)
+	}
+	m.obs[kind] = append(m.obs[kind], d)
+	m.mu.Unlock()
+}
+
+// since records the duration from start, for use with defer.
+func (m *Metrics) since(kind string, start time.Time) {
+	m.observe(kind, time.Since(start))
+}
+
+// MetricSummary is the distribution of a kind of duration.
+type MetricSummary struct {
+	Count int           `json:"count"`
+	Total time.Duration `json:"total_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+func (m *Metrics) summary() map[string]MetricSummary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sums := make(map[string]MetricSummary, len(m.obs))
+	for kind, ds := range m.obs {
+		sorted := append([]time.Duration(nil), ds...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		s := MetricSummary{
+			Count: len(sorted),
+			P50:   percentile(sorted, 0.50),
+			P90:   percentile(sorted, 0.90),
+			P99:   percentile(sorted, 0.99),
+			Max:   sorted[len(sorted)-1],
+		}
+		for _, d := range sorted {
+			s.Total += d
+		}
+		sums[kind] = s
+	}
+	return sums
+}
+
+// print writes the summary as a table.
+func (m *Metrics) print(w io.Writer) {
+	sums := m.summary()
+	m.mu.Lock()
+	keys := append([]string(nil), m.keys...)
+	m.mu.Unlock()
+	fmt.Fprintf(w, "%-10s %8s %10s %10s %10s %10s %10s\n", "METRIC", "COUNT", "TOTAL", "P50", "P90", "P99", "MAX")
+	for _, k := range keys {
+		s := sums[k]
+		fmt.Fprintf(w, "%-10s %8d %10v %10v %10v %10v %10v\n", k, s.Count,
+			s.Total.Round(time.Millisecond), s.P50.Round(time.Millisecond), s.P90.Round(time.Millisecond),
+			s.P99.Round(time.Millisecond), s.Max.Round(time.Millisecond))
+	}
+}
+
+// servePprof serves the profiling endpoints at addr, e.g. localhost:6060.
+func servePprof(addr string) {
+	go func() {
+		if err := http.ListenAndServe(addr, nil); err != nil {
+			report.warnf("pprof", "%v", err)
+		}
+	}()
+}
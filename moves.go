@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+const capitalMovesFile = "capital_moves.txt"
+
+// CapitalMove is a curated relocation of a country's capital.
+type CapitalMove struct {
+	Former string // former capital
+	Year   string // year of the move
+}
+
+// capitalMoves are the relocations by country name, read from
+// capital_moves.txt with --capital-moves.
+var capitalMoves map[string]CapitalMove
+
+// readCapitalMoves parses the curated list, one move per line as
+// "country | former capital | year", skipping blank lines and # comments.
+func readCapitalMoves(path string) (map[string]CapitalMove, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	moves := make(map[string]CapitalMove)
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: want 3 fields, got %d", path, i+1, len(fields))
+		}
+		moves[strings.TrimSpace(fields[0])] = CapitalMove{
+			Former: strings.TrimSpace(fields[1]),
+			Year:   strings.TrimSpace(fields[2]),
+		}
+	}
+	return moves, nil
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("former-capital").Parse(`{{template "front-matter" front nil .Tags}}What was the capital of **{{.Name}}** before {{.Capital}}?
+<!--question-->
+**{{.CapitalMove.Former}}** *(until {{.CapitalMove.Year}})*`))
+	tmpls = template.Must(tmpls.New("capital-move").Parse(`{{template "front-matter" front nil .Tags}}In which year did **{{.Name}}** move its capital from {{.CapitalMove.Former}} to {{.Capital}}?
+<!--question-->
+**{{.CapitalMove.Year}}**`))
+}
+
+// extractCapitalMove merges the curated relocation into the country.
+func extractCapitalMove(c *Country, src *Source) error {
+	if m, ok := capitalMoves[c.Name]; ok {
+		c.CapitalMove = &m
+	}
+	return nil
+}
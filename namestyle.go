@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Name styles, which form of the country name cards ask about.
+const (
+	nameStyleCommon   = "common"   // North Korea, as listed
+	nameStyleOfficial = "official" // Democratic People's Republic of Korea
+)
+
+func validNameStyle(style string) error {
+	switch style {
+	case nameStyleCommon, nameStyleOfficial:
+		return nil
+	}
+	return fmt.Errorf("invalid name style %q, want %s or %s", style, nameStyleCommon, nameStyleOfficial)
+}
+
+// officialName returns the english official name, empty if wikidata has
+// none.
+func officialName(e *Entity) string {
+	for _, t := range e.Texts("P1448") { // official name
+		if t.Language == "en" {
+			return t.Value
+		}
+	}
+	return ""
+}
+
+// applyNameStyle names the country by the --name-style policy, accepting the
+// other form as an alternative answer. Countries without an english official
+// name keep the common name.
+func applyNameStyle(c *Country, e *Entity) {
+	official := officialName(e)
+	if official == "" {
+		if *flagNameStyle == nameStyleOfficial {
+			report.fallback(c.UName, "official name")
+		}
+		return
+	}
+	if strings.EqualFold(official, c.Name) {
+		return
+	}
+	other := official
+	if *flagNameStyle == nameStyleOfficial {
+		c.Name, other = official, c.Name
+	}
+	addAltName(c, other)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// officialEntity returns an item with the official names.
+func officialEntity(names ...wdText) *Entity {
+	e := &Entity{Claims: make(map[string][]wdStatement)}
+	for _, n := range names {
+		var st wdStatement
+		st.Rank = "normal"
+		st.Mainsnak.Datavalue.Type = "monolingualtext"
+		st.Mainsnak.Datavalue.Value, _ = json.Marshal(map[string]string{"text": n.Value, "language": n.Language})
+		e.Claims["P1448"] = append(e.Claims["P1448"], st)
+	}
+	return e
+}
+
+func TestApplyNameStyle(t *testing.T) {
+	korea := officialEntity(
+		wdText{Language: "ko", Value: "조선민주주의인민공화국"},
+		wdText{Language: "en", Value: "Democratic People's Republic of Korea"},
+	)
+	tests := []struct {
+		style    string
+		entity   *Entity
+		alts     []string
+		name     string
+		wantAlts []string
+	}{
+		{nameStyleCommon, korea, nil, "North Korea", []string{"Democratic People's Republic of Korea"}},
+		{nameStyleOfficial, korea, []string{"DPRK"}, "Democratic People's Republic of Korea", []string{"North Korea", "DPRK"}},
+		// The official name is already an alternative.
+		{nameStyleOfficial, korea, []string{"Democratic People's Republic of Korea"}, "Democratic People's Republic of Korea", []string{"North Korea"}},
+		// No english official name, the common name is kept.
+		{nameStyleOfficial, officialEntity(wdText{Language: "ko", Value: "조선"}), nil, "North Korea", nil},
+		{nameStyleOfficial, officialEntity(wdText{Language: "en", Value: "north korea"}), nil, "North Korea", nil},
+	}
+	old := *flagNameStyle
+	defer func() { *flagNameStyle = old }()
+	for _, tt := range tests {
+		*flagNameStyle = tt.style
+		c := &Country{Name: "North Korea", UName: "North_Korea", AltNames: tt.alts}
+		applyNameStyle(c, tt.entity)
+		if c.Name != tt.name || !reflect.DeepEqual(c.AltNames, tt.wantAlts) {
+			t.Errorf("%s %v: got %q %q, want %q %q", tt.style, tt.alts, c.Name, c.AltNames, tt.name, tt.wantAlts)
+		}
+	}
+}
+
+func TestNameStyleKeepsPaths(t *testing.T) {
+	oldStyle, oldLayout := *flagNameStyle, *flagLayout
+	defer func() { *flagNameStyle, *flagLayout = oldStyle, oldLayout }()
+	*flagNameStyle, *flagLayout = nameStyleOfficial, layoutCountry
+
+	c := &Country{Name: "North Korea", UName: "North_Korea"}
+	applyNameStyle(c, officialEntity(wdText{Language: "en", Value: "Democratic People's Republic of Korea"}))
+	if got, want := deckDir(c, "flags"), filepath.Join("countries", "North_Korea"); got != want {
+		t.Errorf("deckDir = %q, want %q", got, want)
+	}
+}
+
+func TestAddAltName(t *testing.T) {
+	tests := []struct {
+		alts []string
+		add  string
+		want []string
+	}{
+		{nil, "Gambia", []string{"Gambia"}},
+		{[]string{"Gambia", "Republic of the Gambia"}, "Gambia", []string{"Gambia", "Republic of the Gambia"}},
+		{[]string{"the gambia", "Republic of the Gambia"}, "Gambia", []string{"Gambia", "Republic of the Gambia"}},
+		{[]string{"GAMBIA"}, "Gambia", []string{"Gambia"}},
+	}
+	for _, tt := range tests {
+		c := &Country{Name: "The Gambia", AltNames: tt.alts}
+		addAltName(c, tt.add)
+		if !reflect.DeepEqual(c.AltNames, tt.want) {
+			t.Errorf("addAltName(%q, %q) = %q, want %q", tt.alts, tt.add, c.AltNames, tt.want)
+		}
+	}
+}
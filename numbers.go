@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Number styles of numeric answers, by digit grouping convention.
+const (
+	numberEN    = "en"    // 67,800,000
+	numberDE    = "de"    // 67.800.000
+	numberFR    = "fr"    // 67 800 000
+	numberCH    = "ch"    // 67'800'000
+	numberShort = "short" // 67.8 million
+)
+
+// numberSeparators are the thousands separators of the styles, fr uses a
+// narrow no-break space.
+var numberSeparators = map[string]string{
+	numberEN:    ",",
+	numberDE:    ".",
+	numberFR:    " ",
+	numberCH:    "'",
+	numberShort: ",",
+}
+
+func numberStyles() string {
+	var styles []string
+	for s := range numberSeparators {
+		styles = append(styles, s)
+	}
+	sort.Strings(styles)
+	return strings.Join(styles, ", ")
+}
+
+// scaleUnit is a named power of a thousand, e.g. 1e9 billion.
+type scaleUnit struct {
+	scale float64
+	name  string
+}
+
+// shortUnits abbreviate the short number style.
+var shortUnits = []scaleUnit{{1e9, "billion"}, {1e6, "million"}}
+
+// formatScaled formats n to three significant figures in the largest of
+// the units, largest first, that it reaches, e.g. 67800000 -> "67.8
+// million". Values rounding up to a thousand move to the next unit, e.g.
+// 999960000 -> "1 billion". ok is false below the smallest unit.
+func formatScaled(n float64, units []scaleUnit) (s string, ok bool) {
+	for i, u := range units {
+		if n < u.scale {
+			continue
+		}
+		v := roundSignificant(n/u.scale, 3)
+		if v >= 1000 && i > 0 {
+			u = units[i-1]
+			v = roundSignificant(n/u.scale, 3)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64) + " " + u.name, true
+	}
+	return "", false
+}
+
+// roundSignificant rounds x to the significant figures.
+func roundSignificant(x float64, figures int) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(x, 'g', figures, 64), 64)
+	return v
+}
+
+// formatNumber formats n per --number-style, abbreviating millions and
+// billions with the short style, e.g. 67800000 -> "67.8 million".
+func formatNumber(n float64) string {
+	if *flagNumberStyle == numberShort {
+		if s, ok := formatScaled(n, shortUnits); ok {
+			return s
+		}
+	}
+	return formatInt(n)
+}
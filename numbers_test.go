@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		n    float64
+		want string
+	}{
+		{2782905000000, "$2.78 trillion"},
+		{999.96e9, "$1 trillion"},
+		{999.4e9, "$999 billion"},
+		{1e9, "$1 billion"},
+		{45.26e6, "$45.3 million"},
+		{999995e3, "$1 billion"},
+		{24500, "$24,500"},
+		{2.5e15, "$2500 trillion"},
+	}
+	for _, tt := range tests {
+		if got := formatMoney(tt.n); got != tt.want {
+			t.Errorf("formatMoney(%v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	old := *flagNumberStyle
+	defer func() { *flagNumberStyle = old }()
+	tests := []struct {
+		style string
+		n     float64
+		want  string
+	}{
+		{numberShort, 67800000, "67.8 million"},
+		{numberShort, 999.96e6, "1 billion"},
+		{numberShort, 1411750000, "1.41 billion"},
+		{numberShort, 999999, "999,999"},
+		{numberEN, 67800000, "67,800,000"},
+		{numberDE, 67800000, "67.800.000"},
+		{numberCH, 1234, "1'234"},
+	}
+	for _, tt := range tests {
+		*flagNumberStyle = tt.style
+		if got := formatNumber(tt.n); got != tt.want {
+			t.Errorf("formatNumber(%v) with %s = %q, want %q", tt.n, tt.style, got, tt.want)
+		}
+	}
+}
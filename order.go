@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sort"
+)
+
+// Order is a suggested study order of a deck's cards, written to order.json
+// for importers that honor ordering.
+type Order struct {
+	Deck  string       `json:"deck"`
+	Cards []OrderEntry `json:"cards"`
+}
+
+// OrderEntry is a card in study order. Cards of neighboring countries share
+// a group.
+type OrderEntry struct {
+	File       string `json:"file"`
+	Country    string `json:"country"`
+	Continent  string `json:"continent,omitempty"`
+	Difficulty string `json:"difficulty,omitempty"`
+	Group      int    `json:"group"`
+}
+
+var difficultyRank = map[string]int{
+	"beginner":     0,
+	"intermediate": 1,
+	"advanced":     2,
+}
+
+func firstContinent(c *Country) string {
+	if len(c.Continents) > 0 {
+		return c.Continents[0]
+	}
+	return ""
+}
+
+// studyOrder orders the countries by continent, then difficulty and
+// population. Neighbors on the same continent follow each other.
+func studyOrder(countries []Country) ([]*Country, []int) {
+	sorted := make([]*Country, len(countries))
+	for i := range countries {
+		sorted[i] = &countries[i]
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if ca, cb := firstContinent(a), firstContinent(b); ca != cb {
+			return ca < cb
+		}
+		if da, db := difficultyRank[a.Difficulty], difficultyRank[b.Difficulty]; da != db {
+			return da < db
+		}
+		if a.Population != b.Population {
+			return a.Population > b.Population
+		}
+		return a.Name < b.Name
+	})
+
+	index := make(map[string]int, len(sorted))
+	for i, c := range sorted {
+		index[c.UName] = i
+	}
+	placed := make([]bool, len(sorted))
+	var order []*Country
+	var groups []int
+	group := 0
+	for i, c := range sorted {
+		if placed[i] {
+			continue
+		}
+		group++
+		placed[i] = true
+		order = append(order, c)
+		groups = append(groups, group)
+		var next []int
+		for _, n := range c.Neighbors {
+			j, ok := index[toURLName(n)]
+			if ok && !placed[j] && firstContinent(sorted[j]) == firstContinent(c) {
+				next = append(next, j)
+			}
+		}
+		sort.Ints(next)
+		for _, j := range next {
+			placed[j] = true
+			order = append(order, sorted[j])
+			groups = append(groups, group)
+		}
+	}
+	return order, groups
+}
+
+// makeOrders writes the order.json of each enabled country deck. The country
+// layout has no deck directories so is left unordered.
+func makeOrders(countries []Country) error {
+	if *flagLayout == layoutCountry {
+		return nil
+	}
+	order, groups := studyOrder(countries)
+	for _, t := range enabledCardTypes() {
+		ct, ok := t.(*countryCards)
+		if !ok || ct.tmpl == "" {
+			continue
+		}
+		// Decks may be split across directories by layout.
+		orders := make(map[string]*Order)
+		var dirs []string
+		for i, c := range order {
+			if ct.has != nil && !ct.has(c) {
+				continue
+			}
+			dir, name := cardPath(c, ct.deck(), countryFileName(c)+ct.suffix, ct.tmpl)
+			o, ok := orders[dir]
+			if !ok {
+				o = &Order{Deck: ct.name}
+				orders[dir] = o
+				dirs = append(dirs, dir)
+			}
+			o.Cards = append(o.Cards, OrderEntry{
+				File:       cardName(name) + ".md",
+				Country:    c.Name,
+				Continent:  firstContinent(c),
+				Difficulty: c.Difficulty,
+				Group:      groups[i],
+			})
+		}
+		for _, dir := range dirs {
+			b, err := json.MarshalIndent(orders[dir], "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := writeOutput(filepath.Join(dir, "order.json"), append(b, '\n')); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
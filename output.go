@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Naming policies for generated card files.
+const (
+	namingWiki = "wiki" // wikipedia page names, e.g. United_States
+	namingSlug = "slug" // lowercase ascii, e.g. united-states
+)
+
+// foldLatin maps accented latin letters to ascii.
+var foldLatin = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+// slug formats the name as lowercase ascii words joined by dashes, e.g.
+// "São_Tomé_and_Príncipe" -> "sao-tome-and-principe".
+func slug(name string) string {
+	s := foldLatin.Replace(strings.ToLower(name))
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+			// Elide apostrophes, e.g. cote-divoire.
+		default:
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// cardName applies the naming policy to a card file name.
+func cardName(name string) string {
+	if *flagNaming == namingSlug {
+		return slug(name)
+	}
+	return safeFileName(toURLName(name))
+}
+
+// maxFileName caps file names in bytes, below the common 255 byte limit to
+// leave room for suffixes.
+const maxFileName = 200
+
+// maxExt is the longest extension kept when shortening a file name.
+const maxExt = 16
+
+// windowsReserved are device names Windows refuses as a file name, with or
+// without an extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// safeFileName makes a generated card or image file name valid on Windows as
+// well as unix: reserved characters are replaced, trailing dots and spaces
+// dropped, reserved device names suffixed and long names shortened with a
+// hash to keep them unique.
+func safeFileName(name string) string {
+	s := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	s = strings.TrimRight(s, ". ")
+
+	ext := filepath.Ext(s)
+	if len(ext) > maxExt {
+		// A dot in a caption, e.g. "Map of St. Lucia showing ...".
+		ext = ""
+	}
+	base := strings.TrimSuffix(s, ext)
+	if windowsReserved[strings.ToUpper(base)] {
+		base += "_"
+	}
+	if len(base)+len(ext) > maxFileName {
+		h := sha1.Sum([]byte(name))
+		suffix := "_" + hex.EncodeToString(h[:4])
+		n := maxFileName - len(ext) - len(suffix)
+		for n > 0 && !utf8.RuneStart(base[n]) {
+			n--
+		}
+		base = base[:n] + suffix
+	}
+	return base + ext
+}
+
+// checkDiffs records outputs that differ from the tree in check mode.
+var (
+	checkDiffs   []string
+	checkDiffsMu sync.Mutex
+)
+
+// writeOutput writes a generated file, in check mode the file is compared
+// against the existing contents instead.
+func writeOutput(path string, b []byte) error {
+	if *flagCheck {
+		old, err := ioutil.ReadFile(path)
+		if err != nil || !bytes.Equal(stripGenerated(old), stripGenerated(b)) {
+			checkDiffsMu.Lock()
+			checkDiffs = append(checkDiffs, path)
+			checkDiffsMu.Unlock()
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0666)
+}
+
+// stripGenerated drops the provenance generation time which changes per run.
+func stripGenerated(b []byte) []byte {
+	i := bytes.Index(b, []byte("\ngenerated: "))
+	if i == -1 {
+		return b
+	}
+	j := bytes.IndexByte(b[i+1:], '\n')
+	if j == -1 {
+		return b[:i]
+	}
+	return append(b[:i:i], b[i+1+j:]...)
+}
+
+// checkResult reports the differing outputs of a check run.
+func checkResult() error {
+	if len(checkDiffs) == 0 {
+		return nil
+	}
+	sort.Strings(checkDiffs)
+	return fmt.Errorf("check failed, %d files differ:\n%s", len(checkDiffs), strings.Join(checkDiffs, "\n"))
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Output layouts of the generated decks.
+const (
+	layoutFlat      = "flat"      // countries/<deck>/<card>
+	layoutContinent = "continent" // countries/<continent>/<deck>/<card>
+	layoutCountry   = "country"   // countries/<country>/<card kind>
+)
+
+// deckDir returns the directory of the country's cards in the deck, media is
+// stored relative to it. Decks are split into difficulty sub-decks by
+// --difficulty=dirs.
+func deckDir(c *Country, deck string) string {
+	dir := "countries"
+	if *flagLayout == layoutCountry {
+		return filepath.Join(dir, cardName(countryFileName(c)))
+	}
+	if *flagLayout == layoutContinent {
+		continent := "other"
+		if len(c.Continents) > 0 {
+			continent = c.Continents[0]
+		}
+		dir = filepath.Join(dir, cardName(continent))
+	}
+	if *flagDifficulty == difficultyDirs {
+		return filepath.Join(dir, deck, c.Difficulty)
+	}
+	return filepath.Join(dir, deck)
+}
+
+// cardPath returns the directory and file name of a country card. In the
+// country layout cards are named by their template, e.g. flag.
+func cardPath(c *Country, deck, name, tmpl string) (string, string) {
+	if *flagLayout == layoutCountry {
+		return deckDir(c, deck), tmpl
+	}
+	return deckDir(c, deck), name
+}
+
+// makeCard renders the country card in the deck.
+func makeCard(c *Country, deck, name, tmpl string) error {
+	dir, name := cardPath(c, deck, name, tmpl)
+	return makeTmpl(dir, name, tmpl, c)
+}
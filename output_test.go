@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSafeFileName(t *testing.T) {
+	tests := map[string]string{
+		"Flag_of_Ghana.svg":            "Flag_of_Ghana.svg",
+		"CON.md":                       "CON_.md",
+		"con":                          "con_",
+		"LPT1.png":                     "LPT1_.png",
+		"CONSOLE.md":                   "CONSOLE.md",
+		`Map: "Africa" <1/2>?*|\.svg`:  "Map_ _Africa_ _1_2_____.svg",
+		"Trailing dots... ":            "Trailing dots",
+		"Tab\there.png":                "Tab_here.png",
+		"Côte_d'Ivoire_(orthographic)": "Côte_d'Ivoire_(orthographic)",
+	}
+	for in, want := range tests {
+		if got := safeFileName(in); got != want {
+			t.Errorf("safeFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSafeFileNameLong(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+	}{
+		{strings.Repeat("São_Tomé_", 40) + "map.svg", ".svg"},
+		{strings.Repeat("é", 150) + ".png", ".png"},
+		// A dot early in a caption isn't an extension.
+		{"Map of St. Lucia " + strings.Repeat("showing the island ", 20), ""},
+		{"A." + strings.Repeat("é", 200), ""},
+	}
+	for _, tt := range tests {
+		got := safeFileName(tt.name)
+		if len(got) > maxFileName {
+			t.Errorf("safeFileName(%.20q...) is %d bytes, want at most %d", tt.name, len(got), maxFileName)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("safeFileName(%.20q...) = %q cut a rune", tt.name, got)
+		}
+		if !strings.HasSuffix(got, tt.ext) {
+			t.Errorf("safeFileName(%.20q...) = %q lost extension %q", tt.name, got, tt.ext)
+		}
+		if safeFileName(tt.name+"x") == got {
+			t.Errorf("safeFileName(%.20q...) not unique", tt.name)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// overridesFile holds reviewed corrections to extracted fields, taking
+// precedence over the built in overrides.
+const overridesFile = "overrides.json"
+
+// Overrides maps country url names to field values, e.g.
+// {"Ivory_Coast": {"capital": "Yamoussoukro"}}. Fields are map, flag and
+// capital.
+type Overrides struct {
+	mu     sync.Mutex
+	fields map[string]map[string]string
+}
+
+var overrides = Overrides{fields: make(map[string]map[string]string)}
+
+func (o *Overrides) read(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := json.Unmarshal(b, &o.fields); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (o *Overrides) write(path string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	b, err := json.MarshalIndent(o.fields, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0666)
+}
+
+func (o *Overrides) get(uname, field string) (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	v, ok := o.fields[uname][field]
+	return v, ok
+}
+
+func (o *Overrides) set(uname, field, value string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.fields[uname] == nil {
+		o.fields[uname] = make(map[string]string)
+	}
+	o.fields[uname][field] = value
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// job is a country moving through the pipeline stages.
+type job struct {
+	idx  int
+	name string
+	src  *Source
+	c    *Country
+	err  error
+}
+
+// stage runs fn on jobs from in with n workers, recording their durations
+// under name. Failed jobs pass through untouched so errors are handled in
+// order by the collector.
+func stage(name string, n int, in <-chan *job, fn func(j *job) error) <-chan *job {
+	if n < 1 {
+		n = 1
+	}
+	out := make(chan *job)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range in {
+				if j.err == nil {
+					start := time.Now()
+					j.err = fn(j)
+					metrics.since(name, start)
+				}
+				out <- j
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// runPipeline generates the countries in three stages: fetching pages is
+// network bound, parsing extracts facts (also fetching wikidata) and
+// rendering is disk bound, each stage runs with its own concurrency. The
+// countries are returned in list order.
+func runPipeline(names []string) ([]Country, error) {
+	done := make(chan struct{})
+	defer close(done)
+
+	in := make(chan *job)
+	go func() {
+		defer close(in)
+		for i, name := range names {
+			select {
+			case in <- &job{idx: i, name: name}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	fetched := stage("fetch", *flagFetchJobs, in, func(j *job) (err error) {
+		progress.publish(ProgressEvent{Index: j.idx, Total: len(names), Country: j.name, Stage: "fetch"})
+		j.src, err = fetchCountry(j.name)
+		return err
+	})
+	parsed := stage("parse", *flagParseJobs, fetched, func(j *job) (err error) {
+		j.c, err = parseCountry(j.name, j.src)
+		return err
+	})
+	rendered := stage("render", *flagRenderJobs, parsed, func(j *job) error {
+		if j.c == nil {
+			return nil // filtered by tags
+		}
+		return renderCountry(j.c, j.src)
+	})
+
+	var jobs []*job
+	for j := range rendered {
+		report.processed(j.name, j.err)
+		e := ProgressEvent{Index: j.idx, Total: len(names), Country: j.name, Stage: "done"}
+		if j.err != nil {
+			e.Stage, e.Error = "failed", j.err.Error()
+		}
+		progress.publish(e)
+		if j.err != nil && !*flagKeepGoing {
+			// Drain the stages so the workers exit.
+			go func() {
+				for range rendered {
+				}
+			}()
+			return nil, fmt.Errorf("%s: %w", j.name, j.err)
+		}
+		if j.err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", j.name, j.err)
+			continue
+		}
+		if j.c != nil {
+			jobs = append(jobs, j)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].idx < jobs[b].idx })
+	results := make([]Country, len(jobs))
+	for i, j := range jobs {
+		results[i] = *j.c
+	}
+	return results, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// populationBuckets are the population options, ascending, parsed from
+// --pop-buckets.
+var populationBuckets []float64
+
+// parseBuckets parses comma separated populations with an optional k, m or b
+// suffix, e.g. "5m,50m,500m".
+func parseBuckets(s string) ([]float64, error) {
+	var buckets []float64
+	for _, v := range strings.Split(s, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		scale := 1.0
+		switch {
+		case strings.HasSuffix(v, "k"):
+			scale = 1e3
+		case strings.HasSuffix(v, "m"):
+			scale = 1e6
+		case strings.HasSuffix(v, "b"):
+			scale = 1e9
+		}
+		n, err := strconv.ParseFloat(strings.TrimRight(v, "kmb"), 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid population bucket %q", v)
+		}
+		buckets = append(buckets, n*scale)
+	}
+	if len(buckets) < 2 {
+		return nil, fmt.Errorf("need at least two population buckets, got %q", s)
+	}
+	sort.Float64s(buckets)
+	return buckets, nil
+}
+
+// formatPopulation formats round populations in words, e.g. 5000000 ->
+// "5 million".
+func formatPopulation(n float64) string {
+	for _, u := range []struct {
+		scale float64
+		name  string
+	}{{1e9, "billion"}, {1e6, "million"}, {1e3, "thousand"}} {
+		if n >= u.scale {
+			return strconv.FormatFloat(n/u.scale, 'f', -1, 64) + " " + u.name
+		}
+	}
+	return formatInt(n)
+}
+
+// closestBucket returns the bucket nearest the population on a log scale, so
+// 15 million is closer to 5 million than 50 million.
+func closestBucket(population float64, buckets []float64) float64 {
+	best := buckets[0]
+	for _, b := range buckets[1:] {
+		if math.Abs(math.Log(population/b)) < math.Abs(math.Log(population/best)) {
+			best = b
+		}
+	}
+	return best
+}
+
+// joinOr joins the options as a list, e.g. "a, b or c".
+func joinOr(options []string) string {
+	if len(options) < 2 {
+		return strings.Join(options, "")
+	}
+	return strings.Join(options[:len(options)-1], ", ") + " or " + options[len(options)-1]
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("population").Funcs(template.FuncMap{
+		"populationOptions": func() string {
+			var options []string
+			for _, b := range populationBuckets {
+				options = append(options, formatPopulation(b))
+			}
+			return joinOr(options)
+		},
+	}).Parse(`{{template "front-matter" front nil .Tags}}Is the population of **{{.Name}}** closer to {{populationOptions}}?
+<!--question-->
+**{{.PopulationBucket}}** *({{int .Population}})*`))
+}
+
+// extractPopulationBucket sets the population bucket of the country, reusing
+// the population if already extracted.
+func extractPopulationBucket(c *Country, src *Source) error {
+	if c.Population == 0 {
+		if err := getStats(c, c.UName, src.Refresh); err != nil {
+			return err
+		}
+	}
+	if c.Population > 0 {
+		c.PopulationBucket = formatPopulation(closestBucket(c.Population, populationBuckets))
+	}
+	return nil
+}
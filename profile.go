@@ -0,0 +1,46 @@
+package main
+
+import (
+	"text/template"
+)
+
+func init() {
+	tmpls = template.Must(tmpls.New("profile").Parse(`{{template "front-matter" front .AltNames .Tags}}Summarise **{{.Name}}**.
+<!--question-->
+![{{.FlagAlt}}]({{.FlagImageURL}})
+
+| | |
+|---|---|
+| Capital | {{.Capital}} |{{if .Population}}
+| Population | {{int .Population}} |{{end}}{{if .Area}}
+| Area | {{area .Area}} |{{end}}{{if .Currencies}}
+| Currency | {{range $i, $v := .Currencies}}{{if $i}}, {{end}}{{$v}}{{end}} |{{end}}{{if .Languages}}
+| Languages | {{range $i, $v := .Languages}}{{if $i}}, {{end}}{{$v}}{{end}} |{{end}}{{if .Neighbors}}
+| Neighbors | {{range $i, $v := .Neighbors}}{{if $i}}, {{end}}{{$v}}{{end}} |{{end}}`))
+}
+
+// getLanguages returns the names of the official languages of the country.
+func getLanguages(uname string, refresh bool) ([]string, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	return getLabels(e.ItemIDs("P37"), refresh) // official language
+}
+
+// extractProfile sets the facts summarised on the profile card, reusing any
+// already extracted by other decks.
+func extractProfile(c *Country, src *Source) (err error) {
+	if c.Population == 0 && c.Area == 0 && c.Neighbors == nil {
+		if err := getStats(c, c.UName, src.Refresh); err != nil {
+			return err
+		}
+	}
+	if c.Currencies == nil {
+		if c.Currencies, err = getCurrencies(c.UName, src.Refresh); err != nil {
+			return err
+		}
+	}
+	c.Languages, err = getLanguages(c.UName, src.Refresh)
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ProgressEvent is a country moving through a run, see the
+// deckcountries.v1.ProgressEvent message.
+type ProgressEvent struct {
+	Index   int    `json:"index"`
+	Total   int    `json:"total"`
+	Country string `json:"country"`
+	Stage   string `json:"stage"` // fetch, done or failed
+	Error   string `json:"error,omitempty"`
+}
+
+// progressHub fans out progress events to subscribers, without any the
+// fetches are printed.
+type progressHub struct {
+	mu   sync.Mutex
+	subs map[chan ProgressEvent]bool
+}
+
+var progress progressHub
+
+// subscribe returns a channel of events until cancel is called. Slow
+// subscribers miss events rather than stall the run.
+func (h *progressHub) subscribe() (<-chan ProgressEvent, func()) {
+	ch := make(chan ProgressEvent, 64)
+	h.mu.Lock()
+	if h.subs == nil {
+		h.subs = make(map[chan ProgressEvent]bool)
+	}
+	h.subs[ch] = true
+	h.mu.Unlock()
+	return ch, func() {
+		h.mu.Lock()
+		delete(h.subs, ch)
+		h.mu.Unlock()
+	}
+}
+
+func (h *progressHub) publish(e ProgressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs) == 0 {
+		if e.Stage == "fetch" {
+			fmt.Println(e.Index, ":", e.Country)
+		}
+		return
+	}
+	for ch := range h.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// generated is the time of this run, stamped on every card. Set
+// SOURCE_DATE_EPOCH for reproducible builds.
+var generated = generatedTime()
+
+func generatedTime() time.Time {
+	if v := os.Getenv("SOURCE_DATE_EPOCH"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(n, 0).UTC()
+		}
+	}
+	return time.Now().UTC()
+}
+
+// Provenance records the sources a card was generated from.
+type Provenance struct {
+	Article     string   // source page url
+	Revision    uint64   // source page revision
+	Files       []string // commons file pages
+	Corrections []string // applied --apply-corrections, e.g. "Capital = Sucre (constitutional)"
+}
+
+func (p *Provenance) provenance() *Provenance { return p }
+
+// provenanceTag starts the footer comment, answers are read up to it.
+const provenanceTag = "<!--provenance"
+
+func init() {
+	tmpls = template.Must(tmpls.New("provenance").Parse(`
+
+` + provenanceTag + `
+{{with .Source}}{{if .Article}}source: {{.Article}}
+{{end}}{{if .Revision}}revision: {{.Revision}}
+{{end}}{{range .Files}}file: {{.}}
+{{end}}{{range .Corrections}}correction: {{.}}
+{{end}}{{end}}generated: {{.Generated.Format "2006-01-02T15:04:05Z"}}
+-->
+`))
+}
+
+func pageURL(host, uname string) string {
+	return "https://" + host + "/wiki/" + escapeURLName(uname)
+}
+
+func filePageURL(name string) string {
+	return "https://commons.wikimedia.org/wiki/File:" + escapeURLName(toURLName(name))
+}
+
+// writeProvenance writes the footer for the card data, cards without a
+// source only record the generation time.
+func writeProvenance(w io.Writer, data interface{}) error {
+	p := &Provenance{}
+	if s, ok := data.(interface{ provenance() *Provenance }); ok {
+		p = s.provenance()
+	}
+	return tmpls.ExecuteTemplate(w, "provenance", struct {
+		Source    *Provenance
+		Generated time.Time
+	}{p, generated})
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"path/filepath"
+	"strings"
+)
+
+// quizChoices is the number of answers offered per question.
+const quizChoices = 4
+
+// QuizCard is a multiple choice question, the wrong answers are drawn from
+// the rest of the deck.
+type QuizCard struct {
+	Prompt string `json:"prompt"`
+	Image  string `json:"image"`
+	Answer string `json:"answer"`
+}
+
+var quizTmpl = template.Must(template.New("quiz").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} quiz</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 1em auto; padding: 0 1em; text-align: center; }
+img { max-width: 100%; max-height: 50vh; margin: 1em 0; }
+button { display: block; width: 100%; margin: .5em 0; padding: .75em; font-size: 1em; cursor: pointer; }
+button.right { background: #9d9; }
+button.wrong { background: #e99; }
+#next { display: none; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p id="score"></p>
+<p id="prompt"></p>
+<img id="image" alt="">
+<div id="choices"></div>
+<button id="next">Next</button>
+<script>
+const cards = {{.Cards}};
+const choices = {{.Choices}};
+let order = [], at = 0, right = 0, done = 0;
+
+function shuffle(a) {
+  for (let i = a.length - 1; i > 0; i--) {
+    const j = Math.floor(Math.random() * (i + 1));
+    [a[i], a[j]] = [a[j], a[i]];
+  }
+  return a;
+}
+
+function score() {
+  document.getElementById("score").textContent = right + " / " + done + " correct, " + (cards.length - done) + " left";
+}
+
+function show() {
+  if (at == order.length) {
+    document.getElementById("prompt").textContent = "Finished! Reload to play again.";
+    document.getElementById("image").style.display = "none";
+    document.getElementById("choices").innerHTML = "";
+    document.getElementById("next").style.display = "none";
+    return;
+  }
+  const card = cards[order[at]];
+  document.getElementById("prompt").textContent = card.prompt;
+  document.getElementById("image").src = card.image;
+  const answers = new Set([card.answer]);
+  for (const i of shuffle(cards.map((_, i) => i))) {
+    if (answers.size == choices) break;
+    answers.add(cards[i].answer);
+  }
+  const box = document.getElementById("choices");
+  box.innerHTML = "";
+  for (const answer of shuffle(Array.from(answers))) {
+    const b = document.createElement("button");
+    b.textContent = answer;
+    b.onclick = () => pick(b, card);
+    box.appendChild(b);
+  }
+  document.getElementById("next").style.display = "none";
+  score();
+}
+
+function pick(b, card) {
+  for (const c of document.getElementById("choices").children) {
+    c.disabled = true;
+    if (c.textContent == card.answer) c.className = "right";
+  }
+  if (b.textContent == card.answer) right++;
+  else b.className = "wrong";
+  done++;
+  at++;
+  score();
+  document.getElementById("next").style.display = "block";
+}
+
+document.getElementById("next").onclick = show;
+order = shuffle(cards.map((_, i) => i));
+show();
+</script>
+</body>
+</html>
+`))
+
+// quizCards returns the cards asking about a single image, e.g. the flag
+// and map decks, with the first answer line as the answer.
+func quizCards(cards []Card) []QuizCard {
+	var quiz []QuizCard
+	for _, c := range cards {
+		images := reMarkdownImage.FindAllStringSubmatch(c.Question, -1)
+		if len(images) != 1 {
+			continue
+		}
+		prompt := strings.TrimSpace(reMarkdownImage.ReplaceAllString(c.Question, ""))
+		answer := strings.Split(plainText(c.Answer), " / ")[0]
+		if answer == "" {
+			continue
+		}
+		quiz = append(quiz, QuizCard{Prompt: plainText(prompt), Image: images[0][2], Answer: answer})
+	}
+	return quiz
+}
+
+// exportQuiz writes a self-contained multiple choice quiz page of the deck's
+// image cards, for sharing without a flashcard app. Decks without enough
+// image cards are skipped.
+func exportQuiz(dir, deck string, cards []Card) error {
+	quiz := quizCards(cards)
+	if len(quiz) < quizChoices {
+		return nil
+	}
+	title := deckFileName(deck)
+	if deck == "." {
+		title = "maps" // the root deck is the maps
+	}
+	var buf bytes.Buffer
+	err := quizTmpl.Execute(&buf, struct {
+		Title   string
+		Cards   []QuizCard
+		Choices int
+	}{title, quiz, quizChoices})
+	if err != nil {
+		return err
+	}
+	return writeOutput(filepath.Join(dir, title+".html"), buf.Bytes())
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/dustin/go-wikiparse"
+)
+
+// maxRedirects caps the redirects followed for a page.
+const maxRedirects = 5
+
+// followRedirects fetches the page following redirects e.g. Bahamas -> The
+// Bahamas, erroring on loops or long chains. The page title is set to the
+// canonical title.
+func followRedirects(uname string, refresh bool) (*wikiparse.Page, error) {
+	seen := map[string]bool{uname: true}
+	chain := []string{uname}
+	page, err := getWikiPage(wikipedia, uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	for page.Redir.Title != "" {
+		uname = toURLName(page.Redir.Title)
+		chain = append(chain, uname)
+		if seen[uname] {
+			return nil, fmt.Errorf("redirect loop: %s", strings.Join(chain, " -> "))
+		}
+		if len(chain) > maxRedirects+1 {
+			return nil, fmt.Errorf("too many redirects: %s", strings.Join(chain, " -> "))
+		}
+		seen[uname] = true
+		if page, err = getWikiPage(wikipedia, uname, refresh); err != nil {
+			return nil, err
+		}
+	}
+
+	title, err := getCanonicalTitle(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	if title != "" {
+		page.Title = title
+	}
+	return page, nil
+}
+
+// getCanonicalTitle normalizes the title with the API, resolving case and
+// underscores, empty if the page is missing.
+func getCanonicalTitle(uname string, refresh bool) (string, error) {
+	return queryCanonicalTitle(fetcher.Query, uname, refresh)
+}
+
+// resolveTitle returns the url name of the page a link target redirects to,
+// the target itself if missing. Responses are locked as they decide list
+// memberships.
+func resolveTitle(uname string, refresh bool) (string, error) {
+	title, err := queryCanonicalTitle(queryLocked, uname, refresh)
+	if err != nil || title == "" {
+		return uname, err
+	}
+	return toURLName(title), nil
+}
+
+func queryCanonicalTitle(query func(key, url string, refresh bool) ([]byte, error), uname string, refresh bool) (string, error) {
+	fname := filepath.Join("pages", wikipedia, "titles", uname+".json")
+	params := url.Values{
+		"action":        {"query"},
+		"titles":        {uname},
+		"redirects":     {"1"},
+		"format":        {"json"},
+		"formatversion": {"2"},
+		"maxlag":        {maxLag},
+	}
+	body, err := query(fname, "https://"+wikipedia+"/w/api.php?"+params.Encode(), refresh)
+	if err != nil {
+		return "", err
+	}
+
+	var rsp struct {
+		Query struct {
+			Pages []struct {
+				Title   string `json:"title"`
+				Missing bool   `json:"missing"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	if err := json.Unmarshal(body, &rsp); err != nil {
+		return "", fmt.Errorf("%s: %w", fname, err)
+	}
+	for _, p := range rsp.Query.Pages {
+		if !p.Missing {
+			return p.Title, nil
+		}
+	}
+	return "", nil
+}
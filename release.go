@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Release files, the manifest records the facts diffed between releases.
+const (
+	versionFile   = "VERSION"
+	changelogFile = "CHANGELOG.md"
+	manifestFile  = "manifest.json"
+)
+
+// ManifestEntry is the released facts of a country.
+type ManifestEntry struct {
+	Capital string `json:"capital"`
+	Flag    string `json:"flag"`
+}
+
+// Manifest maps country names to their released facts.
+type Manifest map[string]ManifestEntry
+
+// Changes between two releases.
+type Changes struct {
+	Version  string
+	Date     string
+	Added    []string
+	Removed  []string
+	Renamed  []string // "Swaziland → Eswatini"
+	Capitals []string // "Country: Old → New"
+	Flags    []string
+}
+
+func (c *Changes) empty() bool {
+	return len(c.Added)+len(c.Removed)+len(c.Renamed)+len(c.Capitals)+len(c.Flags) == 0
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("changelog").Parse(`## {{.Version}} ({{.Date}})
+{{with .Added}}
+### Added
+
+{{range .}}- {{.}}
+{{end}}{{end}}{{with .Removed}}
+### Removed
+
+{{range .}}- {{.}}
+{{end}}{{end}}{{with .Renamed}}
+### Renamed
+
+{{range .}}- {{.}}
+{{end}}{{end}}{{with .Capitals}}
+### Capitals changed
+
+{{range .}}- {{.}}
+{{end}}{{end}}{{with .Flags}}
+### Flags updated
+
+{{range .}}- {{.}}
+{{end}}{{end}}
+`))
+}
+
+func newManifest(countries []Country) Manifest {
+	m := make(Manifest, len(countries))
+	for _, c := range countries {
+		m[c.Name] = ManifestEntry{Capital: c.Capital, Flag: c.FlagName}
+	}
+	return m
+}
+
+func readManifest(path string) (Manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+func manifestNames(m Manifest) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// diffManifests computes the changes from old to new. A removed and an added
+// country with the same capital is a rename.
+func diffManifests(old, new Manifest) *Changes {
+	var c Changes
+	added := make(map[string]bool)
+	for _, name := range manifestNames(new) {
+		if _, ok := old[name]; !ok {
+			added[name] = true
+		}
+	}
+	for _, name := range manifestNames(old) {
+		if _, ok := new[name]; ok {
+			continue
+		}
+		renamed := false
+		for _, a := range manifestNames(new) {
+			if added[a] && new[a].Capital != "" && new[a].Capital == old[name].Capital {
+				c.Renamed = append(c.Renamed, name+" → "+a)
+				delete(added, a)
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			c.Removed = append(c.Removed, name)
+		}
+	}
+	for _, name := range manifestNames(new) {
+		if added[name] {
+			c.Added = append(c.Added, name)
+			continue
+		}
+		o, ok := old[name]
+		if !ok {
+			continue
+		}
+		if n := new[name]; o.Capital != n.Capital {
+			c.Capitals = append(c.Capitals, fmt.Sprintf("%s: %s → %s", name, o.Capital, n.Capital))
+		}
+		if n := new[name]; o.Flag != n.Flag {
+			c.Flags = append(c.Flags, name)
+		}
+	}
+	return &c
+}
+
+// bumpVersion increments the semantic version for the changes: removals and
+// renames are breaking, additions are features and content fixes patches.
+func bumpVersion(version string, c *Changes) (string, error) {
+	var v [3]int
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid version %q", version)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return "", fmt.Errorf("invalid version %q", version)
+		}
+		v[i] = n
+	}
+	switch {
+	case len(c.Removed) > 0 || len(c.Renamed) > 0:
+		v = [3]int{v[0] + 1, 0, 0}
+	case len(c.Added) > 0:
+		v = [3]int{v[0], v[1] + 1, 0}
+	default:
+		v[2]++
+	}
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2]), nil
+}
+
+// release diffs the countries against the last release manifest, bumping
+// VERSION and prepending the changes to CHANGELOG.md. Nothing is written if
+// nothing changed.
+func release(countries []Country) error {
+	old, err := readManifest(manifestFile)
+	if err != nil {
+		return err
+	}
+	manifest := newManifest(countries)
+	changes := diffManifests(old, manifest)
+	if changes.empty() {
+		return nil
+	}
+
+	version := "0.0.0"
+	if b, err := ioutil.ReadFile(versionFile); err == nil {
+		version = string(b)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if changes.Version, err = bumpVersion(version, changes); err != nil {
+		return err
+	}
+	changes.Date = generated.Format("2006-01-02")
+
+	var buf bytes.Buffer
+	if err := tmpls.ExecuteTemplate(&buf, "changelog", changes); err != nil {
+		return err
+	}
+	log, err := ioutil.ReadFile(changelogFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	const header = "# Changelog\n\n"
+	log = append([]byte(header), append(buf.Bytes(), bytes.TrimPrefix(log, []byte(header))...)...)
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		path string
+		b    []byte
+	}{
+		{manifestFile, append(b, '\n')},
+		{versionFile, []byte(changes.Version + "\n")},
+		{changelogFile, log},
+	} {
+		if err := writeOutput(f.path, f.b); err != nil {
+			return err
+		}
+	}
+	fmt.Println("release:", changes.Version)
+	return nil
+}
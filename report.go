@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// Report summarises a run for automated pipelines, written to report.json.
+type Report struct {
+	mu sync.Mutex
+
+	Started         time.Time         `json:"started"`
+	Finished        time.Time         `json:"finished"`
+	Countries       []string          `json:"countries"` // processed
+	CacheHits       int               `json:"cache_hits"`
+	CacheMisses     int               `json:"cache_misses"`
+	BytesDownloaded int64             `json:"bytes_downloaded"`
+	Overrides       []string          `json:"overrides"`
+	Fallbacks       []string          `json:"fallbacks"` // fields left at their defaults
+	Warnings        []string          `json:"warnings"`
+	Anomalies       []string          `json:"anomalies"` // implausible values for review
+	Failures        map[string]string `json:"failures"`  // country to error
+	Skeletons       []string          `json:"skeletons"` // location answers to write
+
+	Metrics map[string]MetricSummary `json:"metrics"` // durations by kind
+}
+
+var report = Report{
+	Started:  time.Now().UTC(),
+	Failures: make(map[string]string),
+}
+
+func (r *Report) cacheHit() {
+	r.mu.Lock()
+	r.CacheHits++
+	r.mu.Unlock()
+}
+
+func (r *Report) download(n int) {
+	r.mu.Lock()
+	r.CacheMisses++
+	r.BytesDownloaded += int64(n)
+	r.mu.Unlock()
+}
+
+func (r *Report) override(uname, field string) {
+	r.mu.Lock()
+	r.Overrides = append(r.Overrides, uname+": "+field)
+	r.mu.Unlock()
+}
+
+// fallback records a field left at its default as the source had no value.
+func (r *Report) fallback(uname, field string) {
+	r.mu.Lock()
+	r.Fallbacks = append(r.Fallbacks, uname+": "+field)
+	r.mu.Unlock()
+}
+
+// warnf records a problem that doesn't stop the country being generated.
+func (r *Report) warnf(uname, format string, args ...interface{}) {
+	msg := uname + ": " + fmt.Sprintf(format, args...)
+	r.mu.Lock()
+	r.Warnings = append(r.Warnings, msg)
+	r.mu.Unlock()
+}
+
+// anomaly records an implausible extracted value for manual review.
+func (r *Report) anomaly(uname, msg string) {
+	r.mu.Lock()
+	r.Anomalies = append(r.Anomalies, uname+": "+msg)
+	r.mu.Unlock()
+}
+
+// skeleton records a location answer still to be written.
+func (r *Report) skeleton(uname string) {
+	r.mu.Lock()
+	r.Skeletons = append(r.Skeletons, uname)
+	r.mu.Unlock()
+}
+
+func (r *Report) processed(name string, err error) {
+	r.mu.Lock()
+	r.Countries = append(r.Countries, name)
+	if err != nil {
+		r.Failures[name] = err.Error()
+	}
+	r.mu.Unlock()
+}
+
+// reset starts a new run's report, for long running servers.
+func (r *Report) reset() {
+	r.mu.Lock()
+	r.Started, r.Finished = time.Now().UTC(), time.Time{}
+	r.Countries, r.Overrides, r.Fallbacks, r.Warnings, r.Anomalies, r.Skeletons = nil, nil, nil, nil, nil, nil
+	r.CacheHits, r.CacheMisses, r.BytesDownloaded = 0, 0, 0
+	r.Failures = make(map[string]string)
+	r.Metrics = nil
+	r.mu.Unlock()
+}
+
+// write saves the report as json.
+func (r *Report) write(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Finished = time.Now().UTC()
+	r.Metrics = metrics.summary()
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0666)
+}
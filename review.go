@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// reviewFields are the fields that can be overridden by review, by prompt
+// key.
+var reviewFields = map[string]string{
+	"c": "capital",
+	"m": "map",
+	"f": "flag",
+}
+
+// review steps through the countries showing the extracted capital, map and
+// flag, prompting to accept or override them. Overrides are saved to the
+// overrides file as they are entered.
+func review(in io.Reader, out io.Writer) error {
+	if err := overrides.read(overridesFile); err != nil {
+		return err
+	}
+	names := []string{*flagCountry}
+	if *flagCountry == "" {
+		var err error
+		if names, err = readCountryList("countries.txt"); err != nil {
+			return err
+		}
+	}
+	var only *regexp.Regexp
+	if *flagOnly != "" {
+		var err error
+		if only, err = regexp.Compile(*flagOnly); err != nil {
+			return fmt.Errorf("invalid only pattern: %w", err)
+		}
+	}
+
+	lines := bufio.NewScanner(in)
+	prompt := func(format string, args ...interface{}) (string, bool) {
+		fmt.Fprintf(out, format, args...)
+		if !lines.Scan() {
+			return "", false
+		}
+		return strings.TrimSpace(lines.Text()), true
+	}
+
+	for i, name := range names {
+		if only != nil && !only.MatchString(name) {
+			continue
+		}
+		uname := toURLName(name)
+		fmt.Fprintf(out, "\n[%d/%d] %s\n", i+1, len(names), name)
+		src, err := fetchCountry(name)
+		var c *Country
+		if err == nil {
+			uname = toURLName(src.Page.Title)
+			c, err = parseCountry(name, src)
+		}
+		if c != nil {
+			fmt.Fprintf(out, "  capital: %s\n", c.Capital)
+			fmt.Fprintf(out, "  map:     %s\n           %s\n", c.MapName, filePageURL(c.MapName))
+			fmt.Fprintf(out, "  flag:    %s\n           %s\n", c.FlagName, filePageURL(c.FlagName))
+		}
+		if err != nil {
+			fmt.Fprintf(out, "  error:   %v\n", err)
+		}
+
+		for {
+			answer, ok := prompt("accept [enter], override (c)apital (m)ap (f)lag, (q)uit: ")
+			if !ok || answer == "q" {
+				return lines.Err()
+			}
+			if answer == "" || answer == "a" {
+				break
+			}
+			field, ok := reviewFields[answer]
+			if !ok {
+				continue
+			}
+			value, ok := prompt("  %s: ", field)
+			if !ok {
+				return lines.Err()
+			}
+			if value == "" {
+				continue
+			}
+			overrides.set(uname, field, value)
+			if err := overrides.write(overridesFile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Plausibility bounds of extracted values, outside them the source page may
+// have been vandalised.
+const (
+	maxNameLen    = 60
+	minPopulation = 500        // Vatican City is ~800, Tuvalu ~11,000
+	maxPopulation = 2000000000 // China and India are ~1.4 billion
+	maxArea       = 20000000.0 // Russia is ~17 million km²
+)
+
+// sanityCheck returns the implausible values extracted for the country.
+func sanityCheck(c *Country) []string {
+	var anomalies []string
+	add := func(format string, args ...interface{}) {
+		anomalies = append(anomalies, fmt.Sprintf(format, args...))
+	}
+	switch n := utf8.RuneCountInString(c.Capital); {
+	case n == 0:
+		add("empty capital")
+	case n > maxNameLen:
+		add("capital is %d characters long", n)
+	}
+	if strings.ContainsAny(c.Capital, "[]{}|<>") {
+		add("capital %q contains markup", c.Capital)
+	}
+	if c.Population != 0 && (c.Population < minPopulation || c.Population > maxPopulation) {
+		add("population %s out of bounds", formatInt(c.Population))
+	}
+	if c.Area < 0 || c.Area > maxArea {
+		add("area %s km² out of bounds", formatInt(c.Area))
+	}
+	for _, f := range []struct{ field, name string }{
+		{"map", c.MapName},
+		{"flag", c.FlagName},
+	} {
+		ext := strings.ToLower(f.name)
+		if !strings.HasSuffix(ext, ".svg") && !strings.HasSuffix(ext, ".png") {
+			add("%s image %q is not svg or png", f.field, f.name)
+		}
+	}
+	return anomalies
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	countriesFile = "countries.json"
+	schemaDir     = "schema"
+	schemaDraft   = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// schemas are the json outputs with a published schema, by schema name.
+var schemas = map[string]interface{}{
+	"countries": []Country{},                    // countries.json
+	"media":     MediaStore{},                   // media/manifest.json
+	"manifest":  Manifest{},                     // manifest.json of releases
+	"deck":      deckManifest{},                 // deck.json of each deck
+	"order":     Order{},                        // order.json of each deck
+	"report":    &Report{},                      // report.json
+	"stats":     Stats{},                        // stats.json
+	"lock":      &Lock{},                        // revisions.lock
+	"overrides": map[string]map[string]string{}, // overrides.json
+}
+
+// writeCountries writes the extracted country data model as json.
+func writeCountries(countries []Country) error {
+	b, err := json.MarshalIndent(countries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutput(countriesFile, append(b, '\n'))
+}
+
+// writeSchemas writes a JSON Schema per json output to dir, derived from the
+// Go types with the encoding/json field rules, for consumers to validate
+// and generate code against.
+func writeSchemas(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := jsonSchema(reflect.TypeOf(schemas[name]))
+		s["$schema"] = schemaDraft
+		s["$id"] = name + ".schema.json"
+		s["title"] = name
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".schema.json"), append(b, '\n'), 0666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// jsonSchema returns the schema of values of t as encoded by encoding/json.
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  []string{"array", "null"},
+			"items": jsonSchema(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 []string{"object", "null"},
+			"additionalProperties": jsonSchema(t.Elem()),
+		}
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		var required []string
+		addFields(t, props, &required)
+		sort.Strings(required)
+		s := map[string]interface{}{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return map[string]interface{}{} // any
+}
+
+// addFields adds the encoded fields of the struct, flattening embedded
+// structs. Fields without omitempty are always present so required.
+func addFields(t reflect.Type, props map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i > -1 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			addFields(f.Type, props, required)
+			continue
+		}
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := jsonSchema(f.Type)
+		if f.Type.Kind() == reflect.Ptr {
+			s = map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+		}
+		props[name] = s
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schemaCommand writes the schemas, see writeSchemas.
+func schemaCommand() error {
+	if err := writeSchemas(schemaDir); err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+	return nil
+}
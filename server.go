@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// The DeckService of proto/deckcountries/v1/deck.proto is served with the
+// Connect protocol's JSON codec, which needs no generated code: unary calls
+// are plain JSON posts and streams are length prefixed JSON envelopes.
+// gRPC clients need a Connect aware proxy.
+const deckService = "/deckcountries.v1.DeckService/"
+
+// connectError is a Connect protocol error, code is one of the Connect
+// codes, e.g. not_found.
+type connectError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *connectError) Error() string { return e.Code + ": " + e.Message }
+
+// connectStatus maps the Connect error codes to their HTTP status.
+var connectStatus = map[string]int{
+	"invalid_argument": http.StatusBadRequest,
+	"not_found":        http.StatusNotFound,
+	"unavailable":      http.StatusServiceUnavailable,
+	"unimplemented":    http.StatusNotFound,
+	"internal":         http.StatusInternalServerError,
+}
+
+// envelopeEndStream flags the last message of a stream.
+const envelopeEndStream = 0x02
+
+// generating serialises generations as the flags and outputs are global.
+var generating = make(chan struct{}, 1)
+
+type generateDeckRequest struct {
+	Only  string `json:"only"`
+	Limit int    `json:"limit"`
+}
+
+type generateDeckResponse struct {
+	Countries []string          `json:"countries"`
+	Failures  map[string]string `json:"failures"`
+}
+
+func generateDeck(req *generateDeckRequest) (*generateDeckResponse, error) {
+	select {
+	case generating <- struct{}{}:
+		defer func() { <-generating }()
+	default:
+		return nil, &connectError{"unavailable", "a generation is already running"}
+	}
+	*flagOnly, *flagLimit = req.Only, req.Limit
+	report.reset()
+	err := run()
+	if *flagReport != "" {
+		if rerr := report.write(*flagReport); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	if err != nil {
+		return nil, &connectError{"internal", err.Error()}
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	return &generateDeckResponse{Countries: report.Countries, Failures: report.Failures}, nil
+}
+
+type getCountryRequest struct {
+	Name string `json:"name"`
+}
+
+type getCountryResponse struct {
+	Country *Country `json:"country"`
+}
+
+// getCountry finds the country in the countries.json of the last
+// generation.
+func getCountry(req *getCountryRequest) (*getCountryResponse, error) {
+	b, err := ioutil.ReadFile(countriesFile)
+	if os.IsNotExist(err) {
+		return nil, &connectError{"not_found", "no generation has run"}
+	}
+	if err != nil {
+		return nil, &connectError{"internal", err.Error()}
+	}
+	var countries []Country
+	if err := json.Unmarshal(b, &countries); err != nil {
+		return nil, &connectError{"internal", fmt.Sprintf("%s: %v", countriesFile, err)}
+	}
+	for i, c := range countries {
+		if c.Name == req.Name || c.UName == toURLName(req.Name) {
+			return &getCountryResponse{Country: &countries[i]}, nil
+		}
+	}
+	return nil, &connectError{"not_found", fmt.Sprintf("country %q not generated", req.Name)}
+}
+
+// unary serves a Connect unary call decoding the request into req.
+func unary(req interface{}, call func() (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeConnectError(w, &connectError{"unimplemented", "want a POST of application/json"})
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil && err != io.EOF {
+			writeConnectError(w, &connectError{"invalid_argument", err.Error()})
+			return
+		}
+		rsp, err := call()
+		if err != nil {
+			writeConnectError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rsp)
+	}
+}
+
+func writeConnectError(w http.ResponseWriter, err error) {
+	ce, ok := err.(*connectError)
+	if !ok {
+		ce = &connectError{"internal", err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(connectStatus[ce.Code])
+	json.NewEncoder(w).Encode(ce)
+}
+
+// writeEnvelope writes a streamed message prefixed by its flags and length.
+func writeEnvelope(w io.Writer, flags byte, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var prefix [5]byte
+	prefix[0] = flags
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(b)))
+	if _, err := w.Write(prefix[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// streamProgress streams the progress events of runs until the client
+// disconnects.
+func streamProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || r.Header.Get("Content-Type") != "application/connect+json" {
+		writeConnectError(w, &connectError{"unimplemented", "want a POST of application/connect+json"})
+		return
+	}
+	io.Copy(ioutil.Discard, r.Body) // the request is empty
+	events, cancel := progress.subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/connect+json")
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	for {
+		select {
+		case e := <-events:
+			if err := writeEnvelope(w, 0, e); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			writeEnvelope(w, envelopeEndStream, struct{}{})
+			return
+		}
+	}
+}
+
+// serve runs the DeckService at addr. Generations write countries.json for
+// GetCountry.
+func serve(addr string) error {
+	*flagJSON = true
+	mux := http.NewServeMux()
+	mux.HandleFunc(deckService+"GenerateDeck", func(w http.ResponseWriter, r *http.Request) {
+		req := new(generateDeckRequest)
+		unary(req, func() (interface{}, error) { return generateDeck(req) })(w, r)
+	})
+	mux.HandleFunc(deckService+"GetCountry", func(w http.ResponseWriter, r *http.Request) {
+		req := new(getCountryRequest)
+		unary(req, func() (interface{}, error) { return getCountry(req) })(w, r)
+	})
+	mux.HandleFunc(deckService+"StreamProgress", streamProgress)
+	fmt.Fprintf(os.Stderr, "serving %s on %s\n", strings.Trim(deckService, "/"), addr)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+)
+
+// skeletonMarker starts the comment marking a generated location answer
+// still to be written by hand.
+const skeletonMarker = "<!-- TODO"
+
+// joinAnd joins the items as a list, e.g. "a, b and c".
+func joinAnd(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// locationSkeleton derives a starting location answer from the continents,
+// subregion and neighbors, marked for an author to finish.
+func locationSkeleton(c *Country, refresh bool) (string, error) {
+	var err error
+	if c.Continents == nil {
+		if c.Continents, err = getContinents(c.UName, refresh); err != nil {
+			return "", err
+		}
+	}
+	if c.Neighbors == nil {
+		if err := getStats(c, c.UName, refresh); err != nil {
+			return "", err
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(skeletonMarker + ": describe where " + c.Name + " is, then remove this line. -->\n")
+	b.WriteString(c.Name + " is a country")
+	if c.Subregion != "" {
+		b.WriteString(" in " + c.Subregion)
+	} else if len(c.Continents) > 0 {
+		b.WriteString(" in " + joinAnd(c.Continents))
+	}
+	b.WriteString(".")
+	if len(c.Neighbors) > 0 {
+		b.WriteString(" It is bordered by " + joinAnd(c.Neighbors) + ".")
+	} else {
+		b.WriteString(" It has no land borders.")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Stats summarises the generated decks for maintainers, written to stats.md
+// and stats.json.
+type Stats struct {
+	Cards      int                 `json:"cards"`
+	Decks      map[string]int      `json:"decks"`       // cards by deck
+	MediaFiles int                 `json:"media_files"` // images and audio
+	MediaBytes int64               `json:"media_bytes"`
+	Overrides  map[string][]string `json:"overrides"` // fields by country
+	Fallbacks  map[string][]string `json:"fallbacks"` // fields by country
+}
+
+var statsTmpl = template.Must(template.New("stats").Funcs(template.FuncMap{
+	"bytes": formatBytes,
+	"join":  strings.Join,
+}).Parse(`# Stats
+
+{{.Cards}} cards in {{len .Decks}} decks, {{.MediaFiles}} media files ({{bytes .MediaBytes}}).
+
+| Deck | Cards |
+|---|---|
+{{range $deck, $n := .Decks}}| {{$deck}} | {{$n}} |
+{{end}}
+## Overrides
+{{if not .Overrides}}
+None.
+{{else}}
+| Country | Fields |
+|---|---|
+{{range $c, $fields := .Overrides}}| {{$c}} | {{join $fields ", "}} |
+{{end}}{{end}}
+## Fallbacks
+{{if not .Fallbacks}}
+None.
+{{else}}
+| Country | Fields |
+|---|---|
+{{range $c, $fields := .Fallbacks}}| {{$c}} | {{join $fields ", "}} |
+{{end}}{{end}}`))
+
+// groupFields groups "uname: field" report entries by country.
+func groupFields(entries []string) map[string][]string {
+	fields := make(map[string][]string)
+	for _, e := range entries {
+		i := strings.Index(e, ": ")
+		if i == -1 {
+			continue
+		}
+		uname, field := e[:i], e[i+2:]
+		if !hasString(fields[uname], field) {
+			fields[uname] = append(fields[uname], field)
+		}
+	}
+	for _, fs := range fields {
+		sort.Strings(fs)
+	}
+	return fields
+}
+
+func hasString(vs []string, s string) bool {
+	for _, v := range vs {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// writeStats counts the cards and media under root and the overrides and
+// fallbacks of the run.
+func writeStats(root string) error {
+	decks, err := readDecks(root)
+	if err != nil {
+		return err
+	}
+	s := Stats{Decks: make(map[string]int)}
+	for deck, cards := range decks {
+		s.Decks[deckFileName(deck)] = len(cards)
+		s.Cards += len(cards)
+	}
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch dir := filepath.Base(filepath.Dir(p)); {
+		case info.IsDir():
+		case dir == "images" || dir == "audio":
+			s.MediaFiles++
+			s.MediaBytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.mu.Lock()
+	s.Overrides = groupFields(report.Overrides)
+	s.Fallbacks = groupFields(report.Fallbacks)
+	report.mu.Unlock()
+
+	b, err := json.MarshalIndent(&s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile("stats.json", append(b, '\n'), 0666); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := statsTmpl.Execute(&buf, &s); err != nil {
+		return err
+	}
+	return ioutil.WriteFile("stats.md", buf.Bytes(), 0666)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+const geoschemePage = "United_Nations_geoscheme"
+
+// === Eastern Africa ===
+var reHeading = regexp.MustCompile(`^(={2,})\s*(.+?)\s*={2,}\s*$`)
+
+// subregionTable is the subregion by country name, scraped once per run.
+var subregionTable struct {
+	sync.Mutex
+	values map[string]string
+	err    error
+}
+
+// getSubregions scrapes the geoscheme page, listing countries under the
+// heading of their subregion. Nested headings are more specific, so the
+// innermost heading above a country is its subregion.
+func getSubregions(refresh bool) (map[string]string, error) {
+	subregionTable.Lock()
+	defer subregionTable.Unlock()
+	if subregionTable.values != nil || subregionTable.err != nil {
+		return subregionTable.values, subregionTable.err
+	}
+	page, err := getLockedWikiPage(wikipedia, geoschemePage, refresh)
+	if err != nil {
+		subregionTable.err = err
+		return nil, err
+	}
+	values := make(map[string]string)
+	var heading string
+	for _, line := range strings.Split(page.Revisions[0].Text, "\n") {
+		if v := reHeading.FindStringSubmatch(line); v != nil {
+			heading = cleanWikiText(v[2])
+			continue
+		}
+		if heading == "" || !strings.HasPrefix(line, "*") {
+			continue
+		}
+		name := cleanWikiText(strings.TrimLeft(line, "*# "))
+		if i := strings.Index(name, "("); i > -1 {
+			name = strings.TrimSpace(name[:i])
+		}
+		if _, ok := values[name]; !ok && name != "" {
+			values[name] = heading
+		}
+	}
+	if len(values) == 0 {
+		subregionTable.err = fmt.Errorf("no subregions found in %s", geoschemePage)
+		return nil, subregionTable.err
+	}
+	subregionTable.values = values
+	return values, nil
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("subregion").Parse(`{{template "front-matter" front nil .Tags}}Which UN subregion is **{{.Name}}** in?
+<!--question-->
+**{{.Subregion}}**`))
+}
+
+// extractSubregion sets the UN subregion of the country and tags it, so
+// decks can be filtered by subregion, e.g. --tags=central-asia.
+func extractSubregion(c *Country, src *Source) error {
+	values, err := getSubregions(src.Refresh)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{c.Name, c.Title} {
+		if v, ok := values[name]; ok {
+			c.Subregion = v
+			c.Tags = appendTag(c.Tags, slug(v))
+			return nil
+		}
+	}
+	report.fallback(c.UName, "subregion")
+	return nil
+}
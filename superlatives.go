@@ -0,0 +1,183 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// getStats sets the population, area, coastline and neighbours of the
+// country from wikidata.
+func getStats(c *Country, uname string, refresh bool) error {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return err
+	}
+	if vs := e.Quantities("P1082"); len(vs) > 0 { // population
+		c.Population = vs[0]
+	}
+	if vs := e.Quantities("P2046"); len(vs) > 0 { // area
+		c.Area = vs[0]
+	}
+	if vs := e.Quantities("P5141"); len(vs) > 0 { // coastline
+		c.Coastline = vs[0]
+	}
+	c.Neighbors, err = getNeighbors(e.ItemIDs("P47"), refresh) // shares border with
+	return err
+}
+
+// knownCountries maps the names of countries.txt, with and without their
+// disambiguation, to the listed name. It is read on first use, after the
+// list is checked.
+var knownCountries struct {
+	once  sync.Once
+	names map[string]string
+	err   error
+}
+
+func countryNames() (map[string]string, error) {
+	knownCountries.once.Do(func() {
+		list, err := readCountryList("countries.txt")
+		if err != nil {
+			knownCountries.err = err
+			return
+		}
+		names := make(map[string]string, 2*len(list))
+		for _, name := range list {
+			names[name] = name
+			if i := strings.Index(name, " ("); i > 0 {
+				names[name[:i]] = name // Georgia (country)
+			}
+		}
+		knownCountries.names = names
+	})
+	return knownCountries.names, knownCountries.err
+}
+
+// getNeighbors returns the listed countries of the bordering entities by
+// their english label or aliases. Disputed territories, maritime borders
+// and other entities aren't countries so are dropped.
+func getNeighbors(ids []string, refresh bool) ([]string, error) {
+	names, err := countryNames()
+	if err != nil {
+		return nil, err
+	}
+	neighbors := make([]string, 0, len(ids))
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		e, err := getEntityByID(id, refresh)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := names[e.Label()]
+		for _, a := range e.Aliases["en"] {
+			if ok {
+				break
+			}
+			name, ok = names[a.Value]
+		}
+		if ok && !seen[name] {
+			seen[name] = true
+			neighbors = append(neighbors, name)
+		}
+	}
+	return neighbors, nil
+}
+
+// formatInt formats n with the --number-style thousands separators, e.g.
+// 67800000 -> 67,800,000.
+func formatInt(n float64) string {
+	sep, ok := numberSeparators[*flagNumberStyle]
+	if !ok {
+		sep = ","
+	}
+	s := strconv.FormatInt(int64(n), 10)
+	for i := len(s) - 3; i > 0 && s[i-1] != '-'; i -= 3 {
+		s = s[:i] + sep + s[i:]
+	}
+	return s
+}
+
+// Superlative is a ranked card of the top countries by a value.
+type Superlative struct {
+	Question string
+	Items    []RankItem
+}
+
+type RankItem struct {
+	Name  string
+	Value string
+}
+
+func init() {
+	tmpls = template.Must(tmpls.New("superlative").Parse(`{{.Question}}
+<!--question-->
+{{range $i, $v := .Items}}{{inc $i}}. {{$v.Name}} *({{$v.Value}})*
+{{end}}`))
+}
+
+const superlativeTop = 5
+
+type superlative struct {
+	name     string
+	question string
+	format   func(v float64) string
+	least    bool // rank ascending
+	value    func(c *Country) float64
+}
+
+func formatNeighbors(n float64) string { return formatInt(n) + " neighbors" }
+
+var mostNeighbors = superlative{"most_neighbors", "Which countries border the most countries?", formatNeighbors, false, func(c *Country) float64 { return float64(len(c.Neighbors)) }}
+
+var superlatives = []superlative{
+	{"largest", "Which are the largest countries by area?", formatArea, false, func(c *Country) float64 { return c.Area }},
+	{"smallest", "Which are the smallest countries by area?", formatArea, true, func(c *Country) float64 { return c.Area }},
+	{"most_populous", "Which are the most populous countries?", formatNumber, false, func(c *Country) float64 { return c.Population }},
+	{"least_populous", "Which are the least populous countries?", formatNumber, true, func(c *Country) float64 { return c.Population }},
+	{"longest_coastline", "Which countries have the longest coastline?", formatLength, false, func(c *Country) float64 { return c.Coastline }},
+	mostNeighbors,
+}
+
+// makeSuperlatives renders the ranked cards computed across all countries.
+func makeSuperlatives(countries []Country) error {
+	dir := filepath.Join("countries", "superlatives")
+	for _, sup := range superlatives {
+		if err := makeSuperlative(dir, sup, countries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeSuperlative renders the top countries ranked by the superlative.
+func makeSuperlative(dir string, sup superlative, countries []Country) error {
+	var ranked []*Country
+	for i := range countries {
+		if sup.value(&countries[i]) > 0 {
+			ranked = append(ranked, &countries[i])
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := sup.value(ranked[i]), sup.value(ranked[j])
+		if sup.least {
+			return a < b
+		}
+		return a > b
+	})
+	if len(ranked) > superlativeTop {
+		ranked = ranked[:superlativeTop]
+	}
+
+	s := Superlative{Question: sup.question}
+	for _, c := range ranked {
+		s.Items = append(s.Items, RankItem{
+			Name:  c.Name,
+			Value: sup.format(sup.value(c)),
+		})
+	}
+	return makeTmpl(dir, sup.name, "superlative", &s)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestGetStatsNeighbors(t *testing.T) {
+	f := &memFetcher{}
+	f.addEntity("Q16", "Canada", nil)
+	f.addEntity("Q30", "United States of America|United States|USA", map[string][]string{
+		"P47": {"Q16", "Q96", "Q159", "Q96", "Q1"},
+	}, "United_States")
+	f.addEntity("Q96", "Mexico", nil)
+	f.addEntity("Q159", "Russia", nil) // maritime border
+	f.addEntity("Q1", "Guantanamo Bay Naval Base", nil)
+	withFetcher(t, f)
+	if err := writeOutput("countries.txt", []byte("Canada\nMexico\nUnited States")); err != nil {
+		t.Fatal(err)
+	}
+	knownCountries.once = sync.Once{}
+	defer func() { knownCountries.once = sync.Once{} }()
+
+	c := &Country{Name: "United States", UName: "United_States"}
+	if err := getStats(c, c.UName, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Canada", "Mexico"}; !reflect.DeepEqual(c.Neighbors, want) {
+		t.Errorf("neighbors %q, want %q", c.Neighbors, want)
+	}
+}
+
+func TestGetNeighborsAliases(t *testing.T) {
+	f := &memFetcher{}
+	f.addEntity("Q148", "People's Republic of China|China", nil)
+	f.addEntity("Q230", "Georgia", nil)
+	withFetcher(t, f)
+	if err := writeOutput("countries.txt", []byte("China\nGeorgia (country)")); err != nil {
+		t.Fatal(err)
+	}
+	knownCountries.once = sync.Once{}
+	defer func() { knownCountries.once = sync.Once{} }()
+
+	got, err := getNeighbors([]string{"Q148", "Q230"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"China", "Georgia (country)"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("neighbors %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Symbol is a national symbol of a country, e.g. the national bird.
+type Symbol struct {
+	Provenance
+
+	Country   string `json:"-"` // set when rendered, per --name-style
+	Kind      string // animal, bird or flower
+	Name      string
+	ImageName string // commons file name, empty if none
+	ImageURL  string `json:"-"` // set when rendered
+}
+
+var symbolKinds = []string{"animal", "bird", "flower"}
+
+func init() {
+	tmpls = template.Must(tmpls.New("symbol").Parse(`What is the national {{.Kind}} of **{{.Country}}**?
+<!--question-->
+{{.Name}}{{if .ImageURL}}
+
+![{{.Name}}]({{.ImageURL}}){{end}}`))
+}
+
+// Symbols are modelled as an instance of "national bird" with a qualifier of
+// the country.
+const symbolQuery = `SELECT ?symbolLabel ?kind ?image WHERE {
+  ?symbol p:P31 ?st .
+  ?st ps:P31 ?type ;
+      pq:P642 wd:%s .
+  ?type rdfs:label ?kind .
+  FILTER(LANG(?kind) = "en" && STR(?kind) IN ("national animal", "national bird", "national flower"))
+  OPTIONAL { ?symbol wdt:P18 ?image . }
+  SERVICE wikibase:label { bd:serviceParam wikibase:language "en". }
+}`
+
+// getSymbols returns the national symbols of the country by kind, several
+// symbols of a kind are joined into one.
+func getSymbols(uname string, refresh bool) ([]Symbol, error) {
+	e, err := getEntity(uname, refresh)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := querySPARQL(uname+"_symbols", fmt.Sprintf(symbolQuery, e.ID), refresh)
+	if err != nil {
+		return nil, err
+	}
+	// Query results are unordered.
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i]["symbolLabel"] != rows[j]["symbolLabel"] {
+			return rows[i]["symbolLabel"] < rows[j]["symbolLabel"]
+		}
+		return rows[i]["image"] < rows[j]["image"]
+	})
+
+	// Group multiple symbols of the same kind into one card.
+	byKind := make(map[string]*Symbol)
+	names := make(map[string]map[string]bool)
+	for _, row := range rows {
+		kind := strings.TrimPrefix(row["kind"], "national ")
+		s, ok := byKind[kind]
+		if !ok {
+			s = &Symbol{Kind: kind}
+			s.Article = pageURL(wikidata, e.ID)
+			byKind[kind] = s
+			names[kind] = make(map[string]bool)
+		}
+		if label := row["symbolLabel"]; label != "" && !names[kind][label] {
+			names[kind][label] = true
+			if s.Name != "" {
+				s.Name += ", "
+			}
+			s.Name += label
+		}
+		if s.ImageName == "" && row["image"] != "" {
+			s.ImageName = commonsFileName(row["image"])
+			s.Files = []string{filePageURL(s.ImageName)}
+		}
+	}
+
+	var symbols []Symbol
+	for _, kind := range symbolKinds {
+		if s, ok := byKind[kind]; ok {
+			symbols = append(symbols, *s)
+		}
+	}
+	return symbols, nil
+}
+
+type symbolCards struct{}
+
+func (symbolCards) Name() string  { return "symbols" }
+func (symbolCards) Enabled() bool { return *flagSymbols }
+func (symbolCards) Extract(c *Country, src *Source) (err error) {
+	c.Symbols, err = getSymbols(c.UName, src.Refresh)
+	return err
+}
+func (symbolCards) Render(c *Country, src *Source) error {
+	return makeSymbols(c, deckDir(c, "symbols"), src.Refresh)
+}
+
+func init() {
+	registerCardType(symbolCards{})
+}
+
+func makeSymbols(c *Country, dir string, refresh bool) error {
+	for _, s := range c.Symbols {
+		s.Country = c.Name
+		if s.ImageName != "" {
+			if err := makeImage(dir, s.ImageName, refresh); err != nil {
+				return err
+			}
+			s.ImageURL = imageURL(s.ImageName)
+		}
+		if err := makeTmpl(dir, countryFileName(c)+"_"+s.Kind, "symbol", &s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSymbols(t *testing.T) {
+	f := &memFetcher{Queries: map[string][]byte{
+		filepath.Join("pages", wikidataQuery, "United_States_symbols.json"): []byte(`{"results":{"bindings":[
+{"symbolLabel":{"value":"Bald eagle"},"kind":{"value":"national bird"},"image":{"value":"http://commons.wikimedia.org/wiki/Special:FilePath/Bald%20eagle.jpg"}},
+{"symbolLabel":{"value":"Eagle"},"kind":{"value":"national bird"}},
+{"symbolLabel":{"value":"Bald eagle"},"kind":{"value":"national bird"},"image":{"value":"http://commons.wikimedia.org/wiki/Special:FilePath/Eagle%20in%20flight.jpg"}},
+{"symbolLabel":{"value":"Rose"},"kind":{"value":"national flower"}},
+{"symbolLabel":{"value":"American bison"},"kind":{"value":"national animal"}}
+]}}`),
+	}}
+	f.addEntity("Q30", "United States", nil, "United_States")
+	counting := &countingFetcher{Fetcher: f, files: make(map[string]int)}
+	withFetcher(t, counting)
+
+	c := &Country{Name: "United States", UName: "United_States"}
+	src := &Source{}
+	if err := (symbolCards{}).Extract(c, src); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range c.Symbols {
+		got = append(got, s.Kind+": "+s.Name+" "+s.ImageName)
+	}
+	want := []string{
+		"animal: American bison ",
+		"bird: Bald eagle, Eagle Bald_eagle.jpg",
+		"flower: Rose ",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("symbols %q, want %q", got, want)
+	}
+	if len(counting.files) > 0 {
+		t.Errorf("extract downloaded %v", counting.files)
+	}
+
+	// Rendering only formats, the styled name is asked about.
+	withFetcher(t, &memFetcher{Files: map[string][]byte{"Bald_eagle.jpg": []byte("\xff\xd8\xff\xe0")}})
+	c.Name = "United States of America"
+	if err := (symbolCards{}).Render(c, src); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(deckDir(c, "symbols"), "United_States_bird.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "What is the national bird of **United States of America**?") {
+		t.Errorf("bird card:\n%s", b)
+	}
+}
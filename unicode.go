@@ -0,0 +1,20 @@
+package main
+
+import (
+	"net/url"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// composeName composes letters followed by combining marks to the
+// precomposed (NFC) form that wikipedia titles and commons file hashes use,
+// e.g. "é" -> "é", so names compare and hash the same however they were
+// typed.
+func composeName(s string) string {
+	return norm.NFC.String(s)
+}
+
+// escapeURLName percent-encodes the page or file name for use in a URL path.
+func escapeURLName(uname string) string {
+	return url.PathEscape(uname)
+}
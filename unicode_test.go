@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+func TestToURLName(t *testing.T) {
+	tests := []string{
+		"Côte d'Ivoire",
+		"São Tomé and Príncipe",
+		"México",
+		"Bucureşti",  // cedilla
+		"București",  // comma below
+		"Timișoara",  // comma below
+		"Nouakchott", // plain
+		"Iğdır",      // breve
+		"Brăila",     // breve
+		"Māori",      // macron
+		"Győr",       // double acute
+		"Łódź",       // acute on a letter with a stroke
+		"Gdańsk",
+		"Częstochowa", // ogonek
+	}
+	for _, name := range tests {
+		want := norm.NFC.String(name)
+		for _, in := range []string{name, norm.NFD.String(name)} {
+			got := toURLName(in)
+			if norm.NFC.String(got) != got {
+				t.Errorf("toURLName(%+q) = %+q is not composed", in, got)
+			}
+			if got != toURLName(want) {
+				t.Errorf("toURLName(%+q) = %+q, want %+q", in, got, toURLName(want))
+			}
+		}
+	}
+}
+
+func TestWikiFileURLForms(t *testing.T) {
+	name := "Flag of São Tomé and Príncipe.svg"
+	if got, want := wikiFileURL(norm.NFD.String(name)), wikiFileURL(name); got != want {
+		t.Errorf("decomposed name hashed to %s, want %s", got, want)
+	}
+	const want = "https://upload.wikimedia.org/wikipedia/commons/0/0a/Flag_of_S%C3%A3o_Tom%C3%A9_and_Pr%C3%ADncipe.svg"
+	if got := wikiFileURL(name); got != want {
+		t.Errorf("wikiFileURL(%q) = %s, want %s", name, got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import "fmt"
+
+// Unit systems of area and distance answers.
+const (
+	unitsMetric   = "metric"   // km, km²
+	unitsImperial = "imperial" // mi, mi²
+	unitsBoth     = "both"     // km (mi)
+)
+
+const (
+	kmPerMile     = 1.609344
+	sqKmPerSqMile = kmPerMile * kmPerMile
+)
+
+// formatArea formats an area in km² per --units, e.g. "643,801 km² (248,573
+// mi²)".
+func formatArea(km2 float64) string {
+	return formatUnits(km2, km2/sqKmPerSqMile, "km²", "mi²")
+}
+
+// formatLength formats a distance in km per --units.
+func formatLength(km float64) string {
+	return formatUnits(km, km/kmPerMile, "km", "mi")
+}
+
+func formatUnits(metric, imperial float64, metricUnit, imperialUnit string) string {
+	switch *flagUnits {
+	case unitsImperial:
+		return formatNumber(imperial+0.5) + " " + imperialUnit
+	case unitsBoth:
+		return fmt.Sprintf("%s %s (%s %s)", formatNumber(metric+0.5), metricUnit, formatNumber(imperial+0.5), imperialUnit)
+	}
+	return formatNumber(metric+0.5) + " " + metricUnit
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// bucket is an S3 compatible bucket, GCS is accessed through its XML API
+// with HMAC keys so both are signed with AWS signature version 4.
+type bucket struct {
+	endpoint string // e.g. https://s3.eu-west-1.amazonaws.com
+	region   string
+	name     string
+	prefix   string // key prefix, without trailing slash
+
+	accessKey, secretKey, token string
+}
+
+// parseBucket parses s3://bucket/prefix or gs://bucket/prefix, reading
+// credentials from the environment: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN, AWS_REGION and AWS_ENDPOINT_URL for S3 and
+// GCS_HMAC_ACCESS_ID and GCS_HMAC_SECRET for GCS.
+func parseBucket(dest string) (*bucket, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing bucket in %q", dest)
+	}
+	b := &bucket{name: u.Host, prefix: strings.Trim(u.Path, "/")}
+	switch u.Scheme {
+	case "s3":
+		b.region = os.Getenv("AWS_REGION")
+		if b.region == "" {
+			b.region = "us-east-1"
+		}
+		b.endpoint = os.Getenv("AWS_ENDPOINT_URL")
+		if b.endpoint == "" {
+			b.endpoint = "https://s3." + b.region + ".amazonaws.com"
+		}
+		b.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		b.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		b.token = os.Getenv("AWS_SESSION_TOKEN")
+	case "gs":
+		b.region = "auto"
+		b.endpoint = "https://storage.googleapis.com"
+		b.accessKey = os.Getenv("GCS_HMAC_ACCESS_ID")
+		b.secretKey = os.Getenv("GCS_HMAC_SECRET")
+	default:
+		return nil, fmt.Errorf("unknown bucket scheme %q, want s3 or gs", u.Scheme)
+	}
+	if b.accessKey == "" || b.secretKey == "" {
+		return nil, fmt.Errorf("missing %s credentials in the environment", u.Scheme)
+	}
+	b.endpoint = strings.TrimSuffix(b.endpoint, "/")
+	return b, nil
+}
+
+func (b *bucket) key(name string) string {
+	if b.prefix == "" {
+		return name
+	}
+	return b.prefix + "/" + name
+}
+
+// emptyPayload is the hex sha256 of an empty body.
+const emptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// do sends the signed request for the object key, path style as bucket
+// names with dots break virtual hosted TLS. The body of size bytes is
+// streamed, payload is its hex sha256.
+func (b *bucket) do(method, key string, header http.Header, body io.Reader, size int64, payload string) (*http.Response, error) {
+	p := "/" + uriEncode(b.name+"/"+key)
+	if body == nil || size == 0 {
+		// A zero length body would otherwise be sent chunked.
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, b.endpoint+p, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	b.sign(req, p, payload, time.Now().UTC())
+	start := time.Now()
+	rsp, err := client.Do(req)
+	metrics.since("upload", start)
+	return rsp, err
+}
+
+// sign adds the signature version 4 authorization for the payload's hex
+// sha256, all set headers are signed.
+func (b *bucket) sign(req *http.Request, escapedPath, payload string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	if b.token != "" {
+		req.Header.Set("X-Amz-Security-Token", b.token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n" + escapedPath + "\n\n")
+	for _, k := range names {
+		canonical.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+	canonical.WriteString("\n" + signed + "\n" + payload)
+
+	scope := date + "/" + b.region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical.String()))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := []byte("AWS4" + b.secretKey)
+	for _, s := range []string{date, b.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.accessKey, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// uriEncode escapes all but the unreserved characters and slashes, as
+// signature version 4 requires.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// contentTypes not known to the mime package on every platform.
+var contentTypes = map[string]string{
+	".md":   "text/markdown; charset=utf-8",
+	".json": "application/json",
+	".tsv":  "text/tab-separated-values; charset=utf-8",
+	".org":  "text/plain; charset=utf-8",
+	".zip":  "application/zip",
+}
+
+func contentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := contentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// cacheControl revalidates the cards and manifests as they are regenerated
+// in place, media is cached for a day as commons files are updated under
+// the same name. Content addressed media never changes.
+func cacheControl(name string) string {
+	if reContentName.MatchString(path.Base(name)) {
+		return "public, max-age=31536000, immutable"
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".json", ".txt", ".tsv", ".org", ".zip":
+		return "no-cache"
+	}
+	return "public, max-age=86400"
+}
+
+// uploadFile puts the file unless the object's ETag already matches its
+// md5, returning whether it was uploaded. The file is hashed then streamed
+// so large decks aren't held in memory.
+func (b *bucket) uploadFile(name, file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	md5sum, sha := md5.New(), sha256.New()
+	size, err := io.Copy(io.MultiWriter(md5sum, sha), f)
+	if err != nil {
+		return false, err
+	}
+	sum := md5sum.Sum(nil)
+	key := b.key(name)
+
+	rsp, err := b.do("HEAD", key, nil, nil, 0, emptyPayload)
+	if err != nil {
+		return false, err
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode == 200 && strings.Trim(rsp.Header.Get("ETag"), `"`) == hex.EncodeToString(sum) {
+		return false, nil
+	}
+	if rsp.StatusCode != 200 && rsp.StatusCode != http.StatusNotFound {
+		return false, fmt.Errorf("%s HEAD %s", rsp.Status, key)
+	}
+	if *flagCheck {
+		return true, nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	rsp, err = b.do("PUT", key, http.Header{
+		"Content-Type":  {contentType(name)},
+		"Cache-Control": {cacheControl(name)},
+		"Content-Md5":   {base64.StdEncoding.EncodeToString(sum)},
+	}, f, size, hex.EncodeToString(sha.Sum(nil)))
+	if err != nil {
+		return false, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != 200 {
+		msg, _ := ioutil.ReadAll(rsp.Body)
+		return false, fmt.Errorf("%s PUT %s: %s", rsp.Status, key, bytes.TrimSpace(msg))
+	}
+	return true, nil
+}
+
+// upload syncs the files under root to the bucket at dest, e.g.
+// s3://decks/countries, skipping unchanged objects. Files removed locally
+// are left in the bucket. With --check nothing is uploaded, the changed
+// files are listed.
+func upload(dest, root string) error {
+	b, err := parseBucket(dest)
+	if err != nil {
+		return err
+	}
+	var names []string
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && p != root {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			name, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(name))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		mu       sync.Mutex
+		uploaded []string
+		errs     []string
+		wg       sync.WaitGroup
+	)
+	n := *flagUploadJobs
+	if n < 1 {
+		n = 1
+	}
+	work := make(chan string)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				ok, err := b.uploadFile(name, filepath.Join(root, filepath.FromSlash(name)))
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+				} else if ok {
+					uploaded = append(uploaded, name)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		work <- name
+	}
+	close(work)
+	wg.Wait()
+
+	sort.Strings(uploaded)
+	if *flagCheck {
+		for _, name := range uploaded {
+			fmt.Println(name)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "upload: %d changed, %d unchanged\n", len(uploaded), len(names)-len(uploaded)-len(errs))
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("upload failed:\n%s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const wikidata = "www.wikidata.org"
+
+// Entity is a subset of a wikidata item.
+type Entity struct {
+	ID        string                   `json:"id"`
+	LastRevID uint64                   `json:"lastrevid"`
+	Labels    map[string]wdText        `json:"labels"`
+	Aliases   map[string][]wdText      `json:"aliases"`
+	Claims    map[string][]wdStatement `json:"claims"`
+}
+
+type wdText struct {
+	Language string `json:"language"`
+	Value    string `json:"value"`
+}
+
+type wdSnak struct {
+	Datavalue struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	} `json:"datavalue"`
+}
+
+type wdStatement struct {
+	Mainsnak   wdSnak              `json:"mainsnak"`
+	Rank       string              `json:"rank"`
+	Qualifiers map[string][]wdSnak `json:"qualifiers"`
+}
+
+// Label returns the english label.
+func (e *Entity) Label() string {
+	return e.Labels["en"].Value
+}
+
+// statements returns the best ranked statements for the property.
+func (e *Entity) statements(prop string) []wdStatement {
+	var preferred, normal []wdStatement
+	for _, s := range e.Claims[prop] {
+		switch s.Rank {
+		case "preferred":
+			preferred = append(preferred, s)
+		case "normal":
+			normal = append(normal, s)
+		}
+	}
+	if len(preferred) > 0 {
+		return preferred
+	}
+	return normal
+}
+
+// ItemIDs returns the entity IDs of an item valued property.
+func (e *Entity) ItemIDs(prop string) []string {
+	var ids []string
+	for _, s := range e.statements(prop) {
+		var v struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(s.Mainsnak.Datavalue.Value, &v); err != nil || v.ID == "" {
+			continue
+		}
+		ids = append(ids, v.ID)
+	}
+	return ids
+}
+
+// Strings returns the values of a string or file valued property.
+func (e *Entity) Strings(prop string) []string {
+	var vs []string
+	for _, s := range e.statements(prop) {
+		var v string
+		if err := json.Unmarshal(s.Mainsnak.Datavalue.Value, &v); err != nil || v == "" {
+			continue
+		}
+		vs = append(vs, v)
+	}
+	return vs
+}
+
+// Texts returns the values of a monolingual text property.
+func (e *Entity) Texts(prop string) []wdText {
+	var vs []wdText
+	for _, s := range e.statements(prop) {
+		var v struct {
+			Text     string `json:"text"`
+			Language string `json:"language"`
+		}
+		if err := json.Unmarshal(s.Mainsnak.Datavalue.Value, &v); err != nil || v.Text == "" {
+			continue
+		}
+		vs = append(vs, wdText{Language: v.Language, Value: v.Text})
+	}
+	return vs
+}
+
+// getEntities loads the first entity of the API request, recording its
+// revision under key. In locked mode the recorded revision is fetched.
+func getEntities(key, fname string, params url.Values, refresh bool) (*Entity, error) {
+	if *flagLocked {
+		p, ok := lock.entity(key)
+		if !ok {
+			return nil, fmt.Errorf("wikidata %s: not in %s", key, lockFile)
+		}
+		return getEntityRevision(p.Title, p.Revision)
+	}
+
+	params.Set("action", "wbgetentities")
+	params.Set("props", "info|labels|aliases|claims")
+	params.Set("format", "json")
+	params.Set("maxlag", maxLag)
+	body, err := fetcher.Query(fname, "https://"+wikidata+"/w/api.php?"+params.Encode(), refresh)
+	if err != nil {
+		return nil, err
+	}
+	e, err := parseEntities(fname, body)
+	if err != nil {
+		return nil, err
+	}
+	lock.setEntity(key, LockedPage{Title: e.ID, Revision: e.LastRevID})
+	return e, nil
+}
+
+// getEntityRevision fetches the revision of the item. Revisions never change
+// so are always cached.
+func getEntityRevision(id string, revision uint64) (*Entity, error) {
+	rev := strconv.FormatUint(revision, 10)
+	fname := filepath.Join("pages", wikidata, "revision", rev+".json")
+	body, err := fetcher.Query(fname, "https://"+wikidata+"/wiki/Special:EntityData/"+url.PathEscape(id)+".json?revision="+rev, false)
+	if err != nil {
+		return nil, err
+	}
+	return parseEntities(fname, body)
+}
+
+func parseEntities(fname string, body []byte) (*Entity, error) {
+	var rsp struct {
+		Entities map[string]*Entity `json:"entities"`
+	}
+	if err := json.Unmarshal(body, &rsp); err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	for id, e := range rsp.Entities {
+		if e.ID == "" { // Missing entities are keyed by -1.
+			return nil, fmt.Errorf("%s: missing entity %s", fname, id)
+		}
+		return e, nil
+	}
+	return nil, fmt.Errorf("%s: no entities", fname)
+}
+
+// getEntity loads the wikidata item linked to the english wikipedia page.
+func getEntity(uname string, refresh bool) (*Entity, error) {
+	fname := filepath.Join("pages", wikidata, uname+".json")
+	return getEntities(uname, fname, url.Values{
+		"sites":  {"enwiki"},
+		"titles": {uname},
+	}, refresh)
+}
+
+// getEntityByID loads the wikidata item by ID, e.g. Q90.
+func getEntityByID(id string, refresh bool) (*Entity, error) {
+	fname := filepath.Join("pages", wikidata, id+".json")
+	return getEntities(id, fname, url.Values{
+		"ids": {id},
+	}, refresh)
+}
+
+const wikidataQuery = "query.wikidata.org"
+
+// querySPARQL runs the query against the wikidata query service returning
+// the string value of each binding per result row.
+func querySPARQL(name, query string, refresh bool) ([]map[string]string, error) {
+	fname := filepath.Join("pages", wikidataQuery, name+".json")
+	params := url.Values{
+		"query":  {query},
+		"format": {"json"},
+	}
+	body, err := queryLocked(fname, "https://"+wikidataQuery+"/sparql?"+params.Encode(), refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	var rsp struct {
+		Results struct {
+			Bindings []map[string]struct {
+				Value string `json:"value"`
+			} `json:"bindings"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &rsp); err != nil {
+		return nil, fmt.Errorf("%s: %w", fname, err)
+	}
+	rows := make([]map[string]string, len(rsp.Results.Bindings))
+	for i, b := range rsp.Results.Bindings {
+		row := make(map[string]string, len(b))
+		for k, v := range b {
+			row[k] = v.Value
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// commonsFileName returns the file name of a commons media value, e.g.
+// http://commons.wikimedia.org/wiki/Special:FilePath/Flag%20of%20France.svg
+func commonsFileName(s string) string {
+	if i := strings.LastIndex(s, "/"); i > -1 {
+		s = s[i+1:]
+	}
+	if v, err := url.PathUnescape(s); err == nil {
+		s = v
+	}
+	return toURLName(s)
+}
+
+// Quantities returns the amounts of a quantity valued property.
+func (e *Entity) Quantities(prop string) []float64 {
+	var vs []float64
+	for _, s := range e.statements(prop) {
+		var v struct {
+			Amount string `json:"amount"`
+		}
+		if err := json.Unmarshal(s.Mainsnak.Datavalue.Value, &v); err != nil {
+			continue
+		}
+		f, err := strconv.ParseFloat(v.Amount, 64)
+		if err != nil {
+			continue
+		}
+		vs = append(vs, f)
+	}
+	return vs
+}
+
+// LatestQuantity returns the amount of the quantity valued property with the
+// latest point in time qualifier and its year, zero if unknown. Yearly
+// series like GDP keep older values at the same rank.
+func (e *Entity) LatestQuantity(prop string) (float64, int) {
+	var latest float64
+	var latestYear int
+	for _, s := range e.Claims[prop] {
+		if s.Rank == "deprecated" {
+			continue
+		}
+		var v struct {
+			Amount string `json:"amount"`
+		}
+		if err := json.Unmarshal(s.Mainsnak.Datavalue.Value, &v); err != nil {
+			continue
+		}
+		f, err := strconv.ParseFloat(v.Amount, 64)
+		if err != nil {
+			continue
+		}
+		var year int
+		for _, q := range s.Qualifiers["P585"] { // point in time
+			var t struct {
+				Time string `json:"time"` // +2021-01-01T00:00:00Z
+			}
+			if err := json.Unmarshal(q.Datavalue.Value, &t); err == nil && len(t.Time) >= 5 {
+				year, _ = strconv.Atoi(t.Time[1:5])
+			}
+		}
+		if latestYear == 0 && latest == 0 || year > latestYear {
+			latest, latestYear = f, year
+		}
+	}
+	return latest, latestYear
+}